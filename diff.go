@@ -0,0 +1,170 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// A diffLine is a line of a diff: kind is ' ' if it's in both files, '-' if
+// it's only in the old one and '+' if it's only in the new one.
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// unifiedDiff returns the differences between old and new in the unified
+// format, labeled with the given names, or nil if they are equal.
+func unifiedDiff(oldName, newName string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	lines := diffLines(splitLines(string(old)), splitLines(string(new)))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	// oldLine and newLine are the numbers of the next line in each file.
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(lines); {
+		if lines[i].kind == ' ' {
+			oldLine, newLine = oldLine+1, newLine+1
+			i++
+			continue
+		}
+		// Extend the hunk until there are more than twice the context
+		// lines without changes, so that close changes share a hunk.
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end, same := i, 0
+		for ; end < len(lines) && same <= 2*diffContext; end++ {
+			if lines[end].kind == ' ' {
+				same++
+			} else {
+				same = 0
+			}
+		}
+		if same > diffContext {
+			end -= same - diffContext
+		}
+
+		hunkOld, hunkNew := oldLine-(i-start), newLine-(i-start)
+		var body bytes.Buffer
+		oldCount, newCount := 0, 0
+		for _, l := range lines[start:end] {
+			body.WriteByte(l.kind)
+			body.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+			if l.kind != '+' {
+				oldCount++
+			}
+			if l.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount))
+		buf.Write(body.Bytes())
+
+		for _, l := range lines[i:end] {
+			if l.kind != '+' {
+				oldLine++
+			}
+			if l.kind != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return buf.Bytes()
+}
+
+// hunkRange formats the range of lines of a hunk in one of the files. An
+// empty range is identified by the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits s after each newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest list of lines that turns old into new, found
+// as the longest common subsequence of both. Generated files usually change
+// in a few places, so the common prefix and suffix are skipped first.
+func diffLines(old, new []string) []diffLine {
+	var prefix, suffix []diffLine
+	for len(old) > 0 && len(new) > 0 && old[0] == new[0] {
+		prefix = append(prefix, diffLine{' ', old[0]})
+		old, new = old[1:], new[1:]
+	}
+	for len(old) > 0 && len(new) > 0 && old[len(old)-1] == new[len(new)-1] {
+		suffix = append([]diffLine{{' ', old[len(old)-1]}}, suffix...)
+		old, new = old[:len(old)-1], new[:len(new)-1]
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of old[i:]
+	// and new[j:].
+	lcs := make([][]int32, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			switch {
+			case old[i] == new[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := prefix
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			lines = append(lines, diffLine{' ', old[i]})
+			i, j = i+1, j+1
+		case j == len(new) || (i < len(old) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', old[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', new[j]})
+			j++
+		}
+	}
+	return append(lines, suffix...)
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 )
@@ -25,44 +26,148 @@ var (
 		L:  "L",
 		XL: "XL",
 	}
+
+	_ShirtSizeNames = []string{
+		"NA",
+		"XS",
+		"S",
+		"M",
+		"L",
+		"XL",
+	}
 )
 
-func init() {
-	var v ShirtSize
-	if _, ok := interface{}(v).(fmt.Stringer); ok {
-		_ShirtSizeNameToValue = map[string]ShirtSize{
-			interface{}(NA).(fmt.Stringer).String(): NA,
-			interface{}(XS).(fmt.Stringer).String(): XS,
-			interface{}(S).(fmt.Stringer).String():  S,
-			interface{}(M).(fmt.Stringer).String():  M,
-			interface{}(L).(fmt.Stringer).String():  L,
-			interface{}(XL).(fmt.Stringer).String(): XL,
-		}
+// InvalidShirtSizeError is the error returned when encoding or decoding a
+// value of ShirtSize that isn't the one of any constant.
+type InvalidShirtSizeError struct {
+	// Type is the name of the type, ShirtSize.
+	Type string
+	// Input is the offending input: the JSON or text being decoded, or the
+	// value being encoded, quoted if it's a string.
+	Input string
+	// Valid lists the names of the constants of ShirtSize, in the order
+	// they are declared.
+	Valid []string
+
+	reason string
+}
+
+// Error describes the error along with the offending input.
+func (e *InvalidShirtSizeError) Error() string {
+	if e.reason == "" {
+		return fmt.Sprintf("invalid %s %s", e.Type, e.Input)
+	}
+	return fmt.Sprintf("invalid %s %s: %s", e.Type, e.Input, e.reason)
+}
+
+// _ShirtSizeError returns an InvalidShirtSizeError for the given input.
+func _ShirtSizeError(input, reason string) error {
+	return &InvalidShirtSizeError{
+		Type:   "ShirtSize",
+		Input:  input,
+		Valid:  append([]string(nil), _ShirtSizeNames...),
+		reason: reason,
+	}
+}
+
+// _ShirtSizeValueToJSON contains the JSON encoding of the values of
+// ShirtSize, so they don't need to be encoded every time.
+var _ShirtSizeValueToJSON = map[ShirtSize][]byte{
+	NA: []byte("\"NA\""),
+	XS: []byte("\"XS\""),
+	S:  []byte("\"S\""),
+	M:  []byte("\"M\""),
+	L:  []byte("\"L\""),
+	XL: []byte("\"XL\""),
+}
+
+// String is generated so ShirtSize satisfies fmt.Stringer.
+func (r ShirtSize) String() string {
+	if s, ok := _ShirtSizeValueToName[r]; ok {
+		return s
 	}
+	return fmt.Sprintf("ShirtSize(%d)", r)
 }
 
 // MarshalJSON is generated so ShirtSize satisfies json.Marshaler.
 func (r ShirtSize) MarshalJSON() ([]byte, error) {
-	if s, ok := interface{}(r).(fmt.Stringer); ok {
-		return json.Marshal(s.String())
-	}
-	s, ok := _ShirtSizeValueToName[r]
-	if !ok {
-		return nil, fmt.Errorf("invalid ShirtSize: %d", r)
+	if b, ok := _ShirtSizeValueToJSON[r]; ok {
+		return append([]byte(nil), b...), nil
 	}
-	return json.Marshal(s)
+	return nil, _ShirtSizeError(fmt.Sprintf("%d", r), "")
 }
 
 // UnmarshalJSON is generated so ShirtSize satisfies json.Unmarshaler.
 func (r *ShirtSize) UnmarshalJSON(data []byte) error {
-	var s string
-	if err := json.Unmarshal(data, &s); err != nil {
-		return fmt.Errorf("ShirtSize should be a string, got %s", data)
+	var name []byte
+	if n := len(data); n >= 2 && data[0] == '"' && data[n-1] == '"' && bytes.IndexByte(data, '\\') < 0 {
+		// Strings without escape sequences can be looked up without decoding.
+		name = data[1 : n-1]
+	} else {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return _ShirtSizeError(string(data), "should be a string")
+		}
+		name = []byte(s)
+	}
+	v, ok := _ShirtSizeNameToValue[string(name)]
+	if !ok {
+		return _ShirtSizeError(string(data), "")
 	}
-	v, ok := _ShirtSizeNameToValue[s]
+	*r = v
+	return nil
+}
+
+// MarshalText is generated so ShirtSize satisfies encoding.TextMarshaler.
+func (r ShirtSize) MarshalText() ([]byte, error) {
+	s, ok := _ShirtSizeValueToName[r]
 	if !ok {
-		return fmt.Errorf("invalid ShirtSize %q", s)
+		return nil, _ShirtSizeError(fmt.Sprintf("%d", r), "")
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText is generated so ShirtSize satisfies encoding.TextUnmarshaler.
+func (r *ShirtSize) UnmarshalText(text []byte) error {
+	v, ok := _ShirtSizeNameToValue[string(text)]
+	if !ok {
+		return _ShirtSizeError(string(text), "")
 	}
 	*r = v
 	return nil
 }
+
+// ShirtSizeValues returns all the values of ShirtSize, in the order
+// they are declared.
+func ShirtSizeValues() []ShirtSize {
+	return []ShirtSize{
+		NA,
+		XS,
+		S,
+		M,
+		L,
+		XL,
+	}
+}
+
+// ShirtSizeNames returns the names of all the values of ShirtSize, in
+// the order they are declared.
+func ShirtSizeNames() []string {
+	return append([]string(nil), _ShirtSizeNames...)
+}
+
+// IsValid reports whether r is the value of one of the constants of
+// ShirtSize.
+func (r ShirtSize) IsValid() bool {
+	_, ok := _ShirtSizeValueToName[r]
+	return ok
+}
+
+// ParseShirtSize returns the value of ShirtSize with the given name.
+func ParseShirtSize(name string) (ShirtSize, error) {
+	v, ok := _ShirtSizeNameToValue[name]
+	if !ok {
+		return v, _ShirtSizeError(name, "")
+	}
+	return v, nil
+}
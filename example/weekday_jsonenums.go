@@ -3,69 +3,166 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 )
 
 var (
 	_WeekDayNameToValue = map[string]WeekDay{
-		"Monday":    Monday,
-		"Tuesday":   Tuesday,
-		"Wednesday": Wednesday,
-		"Thursday":  Thursday,
-		"Friday":    Friday,
-		"Saturday":  Saturday,
-		"Sunday":    Sunday,
+		Monday.String():    Monday,
+		Tuesday.String():   Tuesday,
+		Wednesday.String(): Wednesday,
+		Thursday.String():  Thursday,
+		Friday.String():    Friday,
+		Saturday.String():  Saturday,
+		Sunday.String():    Sunday,
 	}
 
 	_WeekDayValueToName = map[WeekDay]string{
-		Monday:    "Monday",
-		Tuesday:   "Tuesday",
-		Wednesday: "Wednesday",
-		Thursday:  "Thursday",
-		Friday:    "Friday",
-		Saturday:  "Saturday",
-		Sunday:    "Sunday",
+		Monday:    Monday.String(),
+		Tuesday:   Tuesday.String(),
+		Wednesday: Wednesday.String(),
+		Thursday:  Thursday.String(),
+		Friday:    Friday.String(),
+		Saturday:  Saturday.String(),
+		Sunday:    Sunday.String(),
+	}
+
+	_WeekDayNames = []string{
+		Monday.String(),
+		Tuesday.String(),
+		Wednesday.String(),
+		Thursday.String(),
+		Friday.String(),
+		Saturday.String(),
+		Sunday.String(),
 	}
 )
 
-func init() {
-	var v WeekDay
-	if _, ok := interface{}(v).(fmt.Stringer); ok {
-		_WeekDayNameToValue = map[string]WeekDay{
-			interface{}(Monday).(fmt.Stringer).String():    Monday,
-			interface{}(Tuesday).(fmt.Stringer).String():   Tuesday,
-			interface{}(Wednesday).(fmt.Stringer).String(): Wednesday,
-			interface{}(Thursday).(fmt.Stringer).String():  Thursday,
-			interface{}(Friday).(fmt.Stringer).String():    Friday,
-			interface{}(Saturday).(fmt.Stringer).String():  Saturday,
-			interface{}(Sunday).(fmt.Stringer).String():    Sunday,
-		}
+// InvalidWeekDayError is the error returned when encoding or decoding a
+// value of WeekDay that isn't the one of any constant.
+type InvalidWeekDayError struct {
+	// Type is the name of the type, WeekDay.
+	Type string
+	// Input is the offending input: the JSON or text being decoded, or the
+	// value being encoded, quoted if it's a string.
+	Input string
+	// Valid lists the names of the constants of WeekDay, in the order
+	// they are declared.
+	Valid []string
+
+	reason string
+}
+
+// Error describes the error along with the offending input.
+func (e *InvalidWeekDayError) Error() string {
+	if e.reason == "" {
+		return fmt.Sprintf("invalid %s %s", e.Type, e.Input)
 	}
+	return fmt.Sprintf("invalid %s %s: %s", e.Type, e.Input, e.reason)
 }
 
+// _WeekDayError returns an InvalidWeekDayError for the given input.
+func _WeekDayError(input, reason string) error {
+	return &InvalidWeekDayError{
+		Type:   "WeekDay",
+		Input:  input,
+		Valid:  append([]string(nil), _WeekDayNames...),
+		reason: reason,
+	}
+}
+
+// _WeekDayValueToJSON contains the JSON encoding of the values of
+// WeekDay, so they don't need to be encoded every time.
+var _WeekDayValueToJSON = func() map[WeekDay][]byte {
+	m := make(map[WeekDay][]byte, len(_WeekDayValueToName))
+	for v, s := range _WeekDayValueToName {
+		m[v], _ = json.Marshal(s)
+	}
+	return m
+}()
+
 // MarshalJSON is generated so WeekDay satisfies json.Marshaler.
 func (r WeekDay) MarshalJSON() ([]byte, error) {
-	if s, ok := interface{}(r).(fmt.Stringer); ok {
-		return json.Marshal(s.String())
+	if b, ok := _WeekDayValueToJSON[r]; ok {
+		return append([]byte(nil), b...), nil
 	}
-	s, ok := _WeekDayValueToName[r]
-	if !ok {
-		return nil, fmt.Errorf("invalid WeekDay: %d", r)
-	}
-	return json.Marshal(s)
+	return nil, _WeekDayError(fmt.Sprintf("%d", r), "")
 }
 
 // UnmarshalJSON is generated so WeekDay satisfies json.Unmarshaler.
 func (r *WeekDay) UnmarshalJSON(data []byte) error {
-	var s string
-	if err := json.Unmarshal(data, &s); err != nil {
-		return fmt.Errorf("WeekDay should be a string, got %s", data)
+	var name []byte
+	if n := len(data); n >= 2 && data[0] == '"' && data[n-1] == '"' && bytes.IndexByte(data, '\\') < 0 {
+		// Strings without escape sequences can be looked up without decoding.
+		name = data[1 : n-1]
+	} else {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return _WeekDayError(string(data), "should be a string")
+		}
+		name = []byte(s)
 	}
-	v, ok := _WeekDayNameToValue[s]
+	v, ok := _WeekDayNameToValue[string(name)]
 	if !ok {
-		return fmt.Errorf("invalid WeekDay %q", s)
+		return _WeekDayError(string(data), "")
 	}
 	*r = v
 	return nil
 }
+
+// MarshalText is generated so WeekDay satisfies encoding.TextMarshaler.
+func (r WeekDay) MarshalText() ([]byte, error) {
+	s, ok := _WeekDayValueToName[r]
+	if !ok {
+		return nil, _WeekDayError(fmt.Sprintf("%d", r), "")
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText is generated so WeekDay satisfies encoding.TextUnmarshaler.
+func (r *WeekDay) UnmarshalText(text []byte) error {
+	v, ok := _WeekDayNameToValue[string(text)]
+	if !ok {
+		return _WeekDayError(string(text), "")
+	}
+	*r = v
+	return nil
+}
+
+// WeekDayValues returns all the values of WeekDay, in the order
+// they are declared.
+func WeekDayValues() []WeekDay {
+	return []WeekDay{
+		Monday,
+		Tuesday,
+		Wednesday,
+		Thursday,
+		Friday,
+		Saturday,
+		Sunday,
+	}
+}
+
+// WeekDayNames returns the names of all the values of WeekDay, in
+// the order they are declared.
+func WeekDayNames() []string {
+	return append([]string(nil), _WeekDayNames...)
+}
+
+// IsValid reports whether r is the value of one of the constants of
+// WeekDay.
+func (r WeekDay) IsValid() bool {
+	_, ok := _WeekDayValueToName[r]
+	return ok
+}
+
+// ParseWeekDay returns the value of WeekDay with the given name.
+func ParseWeekDay(name string) (WeekDay, error) {
+	v, ok := _WeekDayNameToValue[name]
+	if !ok {
+		return v, _WeekDayError(name, "")
+	}
+	return v, nil
+}
@@ -12,13 +12,27 @@
 // limitations under the License.
 
 // JSONenums is a tool to automate the creation of methods that satisfy the
-// fmt.Stringer, json.Marshaler and json.Unmarshaler interfaces.
-// Given the name of a (signed or unsigned) integer type T that has constants
-// defined, jsonenums will create a new self-contained Go source file implementing
+// fmt.Stringer, json.Marshaler, json.Unmarshaler, encoding.TextMarshaler and
+// encoding.TextUnmarshaler interfaces.
+// Given the name of a (signed or unsigned) integer or string type T that has
+// constants defined, jsonenums will create a new self-contained Go source file
+// implementing
 //
 //  func (t T) String() string
 //  func (t T) MarshalJSON() ([]byte, error)
 //  func (t *T) UnmarshalJSON([]byte) error
+//  func (t T) MarshalText() ([]byte, error)
+//  func (t *T) UnmarshalText([]byte) error
+//  func TValues() []T
+//  func TNames() []string
+//  func (t T) IsValid() bool
+//  func ParseT(name string) (T, error)
+//  type InvalidTError struct{ Type, Input string; Valid []string }
+//
+// String is only generated when T doesn't have a String method already; if it
+// does, that method provides the names used in JSON. The choice is made when
+// the code is generated, so the generated methods don't need to check whether
+// T implements fmt.Stringer at run time.
 //
 // The file is created in the same package and directory as the package that defines T.
 // It has helpful defaults designed for use with go generate.
@@ -62,99 +76,830 @@
 // If multiple constants have the same value, the lexically first matching name will
 // be used (in the example, Acetaminophen will print as "Paracetamol").
 //
+// For string types the JSON representation of a constant is its value rather
+// than its name, so given
+//
+//	type Status string
+//
+//	const (
+//		Active   Status = "active"
+//		Disabled Status = "disabled"
+//	)
+//
+// Disabled is encoded as "disabled", and any string other than "active" or
+// "disabled" is rejected when decoding.
+//
 // With no arguments, it processes the package in the current directory.
-// Otherwise, the arguments must name a single directory holding a Go package
-// or a set of Go source files that represent a single Go package.
+// A single argument names a directory holding a Go package. Several
+// arguments, which can be directories, import paths or patterns such as
+// ./..., are loaded together and every package they match is processed; a
+// package that fails doesn't stop the others, but the exit status is
+// non-zero. Packages are loaded the same way the go command loads them, so
+// modules, vendor directories and workspaces are all supported.
 //
 // The -type flag accepts a comma-separated list of types so a single run can
-// generate methods for multiple types. The default output file is
-// t_jsonenums.go, where t is the lower-cased name of the first type listed.
+// generate methods for multiple types. By default each type gets its own
+// output file t_jsonenums.go, where t is the lower-cased name of the type.
 // The suffix can be overridden with the -suffix flag and a prefix may be added
-// with the -prefix flag.
+// with the -prefix flag. The -output flag writes all the types to a single
+// file instead, named as given and relative to the package directory unless
+// it's absolute; -output=- writes to the standard output. The output must be
+// in the package directory, as the generated code belongs to the package.
+//
+// Without -type, the types are the ones whose declaration is marked with a
+// jsonenums:enum directive:
+//
+//	//jsonenums:enum
+//	type Pill int
+//
+// The -all flag selects every integer or string type in the package that has
+// typed constants instead. Either way, files generated by jsonenums are
+// ignored when looking for types and constants, and when several packages are
+// processed those without any such types are skipped.
+//
+// The -transform flag changes how the names of the constants of integer types
+// are represented in JSON: snake, screaming_snake, kebab, lower, upper, camel
+// and pascal are supported. The -trimprefix flag removes a prefix from the
+// names before the transform is applied, so with -trimprefix=Status and
+// -transform=snake the constant StatusInProgress is encoded as "in_progress".
+// Types with a String method keep using it instead.
+//
+// The name of a single constant can be set with a line comment written as a
+// struct tag or with a jsonenums:name directive, and extra names accepted when
+// decoding, but never used when encoding, can be added with jsonenums:alias:
+//
+//	const (
+//		Paracetamol Pill = iota // json:"acetaminophen"
+//		//jsonenums:alias=advil
+//		Ibuprofen //jsonenums:name=ibuprofen
+//	)
+//
+// MarshalText and UnmarshalText share the same names as the JSON methods, so
+// the types can also be used as keys of JSON objects and with any package that
+// relies on encoding.TextMarshaler. They can be left out with -text=false.
+//
+// With -sql the types also implement sql.Scanner and driver.Valuer, so they can
+// be stored in database columns in the same form used in JSON: Value returns
+// the name of the constant, or its value as an int64 with -format=int. Scan
+// accepts names as strings or byte slices and, for integer types, values as
+// integers or strings of digits.
+//
+// With -yaml the types also implement yaml.Marshaler and yaml.Unmarshaler from
+// gopkg.in/yaml.v3, which doesn't use the JSON methods, with the same names
+// and formats as JSON. The generated code then imports gopkg.in/yaml.v3, so
+// the flag should only be used by packages that depend on it already.
+// Packages such as sigs.k8s.io/yaml convert YAML to JSON and need no extra
+// methods.
+//
+// With -xml the types also implement xml.Marshaler, xml.Unmarshaler,
+// xml.MarshalerAttr and xml.UnmarshalerAttr, so they're written in XML
+// elements and attributes as the same text used in JSON, without the quotes.
+//
+// With -format=int integer types are encoded in JSON as their numeric value
+// instead of their name, and decoding fails for any number that isn't the
+// value of one of the constants. String and the text methods keep using the
+// names.
+//
+// The -lenient flag makes UnmarshalJSON accept both a JSON string with the
+// name of a constant and a JSON number with its value, which helps migrating
+// from one format to the other. MarshalJSON keeps using the format selected
+// by -format. Both flags only apply to integer types, and string types are
+// generated as usual when they're given.
+//
+// By default names must match exactly when decoding. The -match flag takes a
+// comma-separated list of normalizations that are tried when they don't:
+// fold ignores case, trim ignores leading and trailing white space, and
+// separators treats hyphens and underscores as the same. With -match=fold,trim
+// the input " XL " decodes as the constant named "xl". Names that become
+// equal once normalized must belong to the same value. For types with a
+// String method this can't be checked when generating the code, so the names
+// that are ambiguous once normalized only match exactly. Encoding always uses
+// the canonical names.
+//
+// With -open, decoding keeps the values that aren't those of any constant
+// instead of failing, and encoding writes them back unchanged. Unknown names of
+// string types and unknown numbers of integer types are stored in the value
+// itself, and unknown numbers are encoded as numbers even without -format=int,
+// so UnmarshalJSON accepts numbers as with -lenient. MarshalText and
+// UnmarshalText use decimal numbers for them too, so they can be map keys. As
+// an integer can't hold an unknown name, the names of integer types are kept
+// in a generated OpenT type instead, whose Value field is the decoded T and
+// whose Unknown field holds the JSON of a name that wasn't recognized.
+//
+// Instead of failing, decoding can use a fallback constant for the names and
+// numbers that aren't those of any constant, such as an Unknown or NA zero
+// value. Mark it with a jsonenums:default directive, or name it with the
+// -fallback flag, which takes precedence:
+//
+//	const (
+//		//jsonenums:default
+//		NA ShirtSize = iota
+//		XS
+//	)
+//
+// Input of the wrong JSON type is still an error. A fallback can't be used
+// with -open.
+//
+// With -flags the types are sets of bit flags, defined by the constants with a
+// single bit set, such as Read Permission = 1 << iota. MarshalJSON encodes a
+// value as the array of the names of its flags, in the order they are
+// declared, so Read|Write becomes ["Read","Write"], and fails if any other
+// bit is set. UnmarshalJSON sets the flags of all the names in an array,
+// which can also be the names of constants combining several flags. The text
+// methods and String join the names with "|". -flags can't be combined with
+// -format=int, -lenient, -open, a fallback, -sql, -yaml or -xml.
+//
+// TValues and TNames return the values and names of all the constants of T in
+// the order they are declared, except for those that repeat the value of an
+// earlier constant. They return a new slice on every call, so callers are
+// free to modify it. IsValid reports whether a value is the one of a
+// constant, and ParseT finds a constant by one of its names, matched as in
+// UnmarshalText, and fails for any other name.
+//
+// The errors returned by the generated methods for values that aren't those
+// of any constant are *InvalidTError, so they can be inspected with
+// errors.As. They hold the name of the type, the offending input and the
+// names of all the constants, which is enough to report a validation error:
+//
+//	var sizeErr *InvalidShirtSizeError
+//	if errors.As(err, &sizeErr) {
+//		http.Error(w, fmt.Sprintf("size must be one of %v", sizeErr.Valid), http.StatusBadRequest)
+//	}
+//
+// With -check nothing is written: the code is generated in memory and
+// compared with the existing files, and the differences are printed as a
+// unified diff. The exit status is non-zero if any file is missing or out of
+// date, so it can be used in pre-commit hooks and CI, as in
+//
+//	jsonenums -check -all ./...
+//
+// Each existing file is generated again with the flags that change the code,
+// such as -format or -match, taken from the command recorded in its header, so
+// the check doesn't need to repeat them. The types and the output files are
+// chosen by the arguments of the check as without it, so packages generated
+// with -type, -output, -prefix or -suffix must be checked with the same flags.
 //
 package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"go/ast"
+	"go/constant"
 	"go/format"
+	goparser "go/parser"
+	"go/token"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/campoy/jsonenums/parser"
+	"golang.org/x/tools/go/ast/astutil"
 )
 
 var (
-	typeNames    = flag.String("type", "", "comma-separated list of type names; must be set")
+	typeNames    = flag.String("type", "", "comma-separated list of type names; default is the types marked with jsonenums:enum")
+	all          = flag.Bool("all", false, "generate for all the integer and string types with typed constants")
+	output       = flag.String("output", "", "output file for all the types, relative to the package directory, or - for stdout; default is one file per type")
 	outputPrefix = flag.String("prefix", "", "prefix to be added to the output file")
 	outputSuffix = flag.String("suffix", "_jsonenums", "suffix to be added to the output file")
+	check        = flag.Bool("check", false, "print a diff and fail if the generated files are out of date instead of writing them")
+
+	// code holds the rest of the flags, which change the generated code.
+	code codeFlags
 )
 
+func init() {
+	code.register(flag.CommandLine)
+}
+
+// codeFlags holds the flags that change the generated code, as opposed to
+// those that choose the types and the output files. -check parses them out of
+// the command recorded in generated files too.
+type codeFlags struct {
+	transform, trimPrefix, format, match, fallback string
+	text, sql, yaml, xml, lenient, open, flags     bool
+}
+
+// register defines the flags in fs.
+func (f *codeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.transform, "transform", "none", "transform applied to the constant names in JSON: "+
+		"none, snake, screaming_snake, kebab, lower, upper, camel or pascal")
+	fs.StringVar(&f.trimPrefix, "trimprefix", "", "prefix to be trimmed from the constant names in JSON")
+	fs.BoolVar(&f.text, "text", true, "generate MarshalText and UnmarshalText too")
+	fs.BoolVar(&f.sql, "sql", false, "generate Scan and Value for database/sql too")
+	fs.BoolVar(&f.yaml, "yaml", false, "generate MarshalYAML and UnmarshalYAML for gopkg.in/yaml.v3 too")
+	fs.BoolVar(&f.xml, "xml", false, "generate the encoding/xml marshaling methods too")
+	fs.StringVar(&f.format, "format", "string", "representation of integer types in JSON: string or int")
+	fs.BoolVar(&f.lenient, "lenient", false, "accept both names and numbers when decoding integer types")
+	fs.StringVar(&f.match, "match", "exact", "comma-separated normalizations applied to names that don't match "+
+		"exactly when decoding: fold, trim and separators")
+	fs.BoolVar(&f.open, "open", false, "keep unknown values when decoding instead of failing")
+	fs.StringVar(&f.fallback, "fallback", "", "constant used when decoding input that isn't the name or value of any constant")
+	fs.BoolVar(&f.flags, "flags", false, "treat the types as bit flags, encoded in JSON as arrays of names")
+}
+
+// newGenerator returns a generator configured by the flags, which records
+// the given command in the generated files. Its package is left unset.
+func (f *codeFlags) newGenerator(command string) (*generator, error) {
+	transform, err := parseTransform(f.transform)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -transform: %v", err)
+	}
+	match, err := parseMatchPolicy(f.match)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -match: %v", err)
+	}
+	if f.format != "string" && f.format != "int" {
+		return nil, fmt.Errorf("invalid -format %q, must be string or int", f.format)
+	}
+	return &generator{
+		command:    command,
+		transform:  transform,
+		trimPrefix: f.trimPrefix,
+		text:       f.text,
+		sql:        f.sql,
+		yaml:       f.yaml,
+		xml:        f.xml,
+		intFormat:  f.format == "int",
+		lenient:    f.lenient,
+		match:      match,
+		open:       f.open,
+		fallback:   f.fallback,
+		flags:      f.flags,
+	}, nil
+}
+
+// recordedGenerator returns a generator configured by the flags in a command
+// recorded in a generated file, ignoring those that don't change the code.
+func recordedGenerator(command string) (*generator, error) {
+	fs := flag.NewFlagSet("jsonenums", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	var f codeFlags
+	f.register(fs)
+	for _, name := range []string{"type", "output", "prefix", "suffix"} {
+		fs.String(name, "", "")
+	}
+	for _, name := range []string{"all", "check"} {
+		fs.Bool(name, false, "")
+	}
+	if err := fs.Parse(strings.Fields(command)); err != nil {
+		return nil, err
+	}
+	return f.newGenerator(command)
+}
+
 func main() {
 	flag.Parse()
-	if len(*typeNames) == 0 {
-		log.Fatalf("the flag -type must be set")
+	if *typeNames != "" && *all {
+		log.Fatalf("the flags -type and -all can't be used together")
 	}
-	types := strings.Split(*typeNames, ",")
-
-	// Only one directory at a time can be processed, and the default is ".".
-	dir := "."
-	if args := flag.Args(); len(args) == 1 {
-		dir = args[0]
-	} else if len(args) > 1 {
-		log.Fatalf("only one directory at a time")
+	base, err := code.newGenerator(strings.Join(os.Args[1:], " "))
+	if err != nil {
+		log.Fatal(err)
 	}
-	dir, err := filepath.Abs(dir)
+
+	dir, patterns := loadPatterns(flag.Args())
+	dir, err = filepath.Abs(dir)
 	if err != nil {
 		log.Fatalf("unable to determine absolute filepath for requested path %s: %v",
 			dir, err)
 	}
 
-	pkg, err := parser.ParsePackage(dir)
+	pkgs, errs, err := parser.ParsePackages(dir, patterns...)
 	if err != nil {
-		log.Fatalf("parsing package: %v", err)
+		log.Fatalf("parsing packages: %v", err)
+	}
+	// Packages are processed independently, so a failure in one of them
+	// doesn't stop the others.
+	for _, err := range errs {
+		log.Printf("parsing package: %v", err)
+	}
+	single := len(pkgs)+len(errs) == 1
+	if len(pkgs) > 1 && (*output == "-" || filepath.IsAbs(*output)) {
+		log.Fatalf("-output=%s can only be used with a single package", *output)
+	}
+	if *check && *output == "-" {
+		log.Fatalf("-output=- can't be used with -check")
 	}
 
-	var analysis = struct {
-		Command        string
-		PackageName    string
-		TypesAndValues map[string][]string
-	}{
-		Command:        strings.Join(os.Args[1:], " "),
-		PackageName:    pkg.Name,
-		TypesAndValues: make(map[string][]string),
+	failed := len(errs) > 0
+	for _, pkg := range pkgs {
+		g := *base
+		g.pkg = pkg
+		if err := processPackage(&g, single); err != nil {
+			log.Printf("%s: %v", pkg.Path, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// loadPatterns returns the directory the packages named by the arguments are
+// loaded from, and the patterns given to go/packages. A single directory is
+// loaded from itself, so that it doesn't need to be part of the module in the
+// current directory. Anything else is a list of directories, import paths or
+// patterns such as ./..., all loaded at once; relative directories are given
+// a ./ prefix, or the go command would take them for import paths.
+func loadPatterns(args []string) (dir string, patterns []string) {
+	if len(args) == 0 {
+		return ".", []string{"."}
+	}
+	if len(args) == 1 && !strings.Contains(args[0], "...") {
+		return args[0], []string{"."}
+	}
+	for _, arg := range args {
+		// Patterns such as a/... are relative to the directory before them.
+		base := arg
+		if i := strings.Index(arg, "..."); i >= 0 {
+			base = arg[:i]
+		}
+		if base != "" && !filepath.IsAbs(arg) && !strings.HasPrefix(arg, ".") {
+			if fi, err := os.Stat(base); err == nil && fi.IsDir() {
+				arg = "./" + filepath.ToSlash(arg)
+			}
+		}
+		patterns = append(patterns, arg)
+	}
+	return ".", patterns
+}
+
+// processPackage generates the code for the types selected in the package of
+// the generator and writes it. Packages without any types are only reported
+// when they are the single package being processed.
+func processPackage(g *generator, single bool) error {
+	var types []string
+	var err error
+	switch {
+	case *typeNames != "":
+		types = strings.Split(*typeNames, ",")
+	case *all:
+		types, err = g.pkg.EnumTypes()
+	default:
+		types, err = g.pkg.MarkedEnumTypes()
+	}
+	if err != nil {
+		return fmt.Errorf("finding types: %v", err)
+	}
+	if len(types) == 0 {
+		if single {
+			return fmt.Errorf("no types found: set -type or -all, or mark types with //jsonenums:enum")
+		}
+		return nil
 	}
 
-	// Run generate for each type.
+	// With -output all the types go in a single file, otherwise each type
+	// gets its own file.
+	if *output != "" {
+		return writeOutput(g, types, *output, g.pkg.Dir)
+	}
 	for _, typeName := range types {
-		values, err := pkg.ValuesOfType(typeName)
+		name := strings.ToLower(*outputPrefix + typeName + *outputSuffix + ".go")
+		if err := writeOutput(g, []string{typeName}, name, g.pkg.Dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOutput generates the code for the given types and writes it to the
+// named file, which is relative to dir unless it is absolute.
+func writeOutput(g *generator, types []string, name, dir string) error {
+	src, err := g.generate(types)
+	if err != nil {
+		return fmt.Errorf("generating code: %v", err)
+	}
+	if name == "-" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	if err := checkOutputDir(name, g.pkg.Dir); err != nil {
+		return err
+	}
+	if *check {
+		return checkOutput(os.Stdout, g, types, name, src)
+	}
+	if err := ioutil.WriteFile(name, src, 0644); err != nil {
+		return fmt.Errorf("writing output: %s", err)
+	}
+	return nil
+}
+
+// checkOutputDir fails if the named file isn't in the package directory, as
+// the generated code wouldn't compile anywhere else.
+func checkOutputDir(name, pkgDir string) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return fmt.Errorf("checking directory of output %s: %v", name, err)
+	}
+	absDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return fmt.Errorf("checking directory of output %s: %v", name, err)
+	}
+	if filepath.Dir(abs) != filepath.Clean(absDir) {
+		return fmt.Errorf("output %s must be in the package directory %s", name, pkgDir)
+	}
+	return nil
+}
+
+// checkOutput compares the code generated for the given types with the
+// contents of the named file, and prints their differences to w if there are
+// any. The code is generated again with the flags that change the code in the
+// command recorded in the file, while the types stay the given ones.
+func checkOutput(w io.Writer, g *generator, types []string, name string, src []byte) error {
+	old, err := ioutil.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading output: %v", err)
+	}
+	if command, ok := recordedCommand(old); ok && command != g.command {
+		recorded, err := recordedGenerator(command)
 		if err != nil {
-			log.Fatalf("finding values for type %v: %v", typeName, err)
+			return fmt.Errorf("parsing the command recorded in %s: %v", name, err)
+		}
+		recorded.pkg = g.pkg
+		if src, err = recorded.generate(types); err != nil {
+			return fmt.Errorf("generating code: %v", err)
 		}
-		analysis.TypesAndValues[typeName] = values
+	}
 
-		var buf bytes.Buffer
-		if err := generatedTmpl.Execute(&buf, analysis); err != nil {
-			log.Fatalf("generating code: %v", err)
+	label := name
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, name); err == nil && !strings.HasPrefix(rel, "..") {
+			label = rel
 		}
+	}
+	diff := unifiedDiff(label, label+" (generated)", old, src)
+	if diff == nil {
+		return nil
+	}
+	w.Write(diff)
+	return fmt.Errorf("%s is out of date", label)
+}
+
+// recordedCommand returns the command written in the header of a file
+// generated by jsonenums.
+func recordedCommand(src []byte) (string, bool) {
+	const prefix, suffix = "// Code generated by jsonenums ", "; DO NOT EDIT."
+	line := string(src)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, suffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(line, prefix), suffix), true
+}
 
-		src, err := format.Source(buf.Bytes())
+// A generator produces the code for the types of a parsed package.
+type generator struct {
+	command string
+	pkg     *parser.Package
+
+	// transform and trimPrefix change the names of the constants of
+	// integer types into their JSON representation.
+	transform  transform
+	trimPrefix string
+
+	// text makes the generated code implement encoding.TextMarshaler and
+	// encoding.TextUnmarshaler too.
+	text bool
+	// sql makes the generated code implement sql.Scanner and driver.Valuer
+	// too.
+	sql bool
+	// yaml makes the generated code implement yaml.Marshaler and
+	// yaml.Unmarshaler from gopkg.in/yaml.v3 too.
+	yaml bool
+	// xml makes the generated code implement xml.Marshaler,
+	// xml.Unmarshaler, xml.MarshalerAttr and xml.UnmarshalerAttr too.
+	xml bool
+
+	// intFormat encodes integer types as their numeric value in JSON
+	// rather than as their name.
+	intFormat bool
+	// lenient accepts both names and numbers when decoding integer types,
+	// whatever their encoding.
+	lenient bool
+	// match is how names that don't match exactly are normalized when
+	// decoding.
+	match matchPolicy
+	// open keeps the values that aren't those of any constant when decoding,
+	// so they can be encoded again.
+	open bool
+	// fallback is the name of the constant used when decoding input that
+	// isn't the name or value of any constant. It overrides the constants
+	// marked with a jsonenums:default directive.
+	fallback string
+	// flags treats the types as sets of bit flags, each of them defined by
+	// a constant with a single bit set.
+	flags bool
+}
+
+// A typeAnalysis holds what the template needs to know about a single type.
+type typeAnalysis struct {
+	Name string
+	// IsString reports whether the underlying type is a string.
+	IsString bool
+	// GenerateString is set when the type has no String method, so one is
+	// generated.
+	GenerateString bool
+	// IsStringer reports whether the type implements fmt.Stringer, so some of
+	// the names used in JSON are only known at run time.
+	IsStringer bool
+	// Underlying is the name of the basic type underlying the type.
+	Underlying string
+	// IntFormat is set when the values are encoded in JSON as integers, which
+	// is never the case for string types.
+	IntFormat bool
+	// AcceptNames and AcceptNumbers list what UnmarshalJSON accepts.
+	AcceptNames   bool
+	AcceptNumbers bool
+	// Fallback is the name of the constant used when decoding input that
+	// isn't the name or value of any constant, if any.
+	Fallback string
+	// Mask is the combination of all the flags of the type, in -flags mode.
+	Mask   string
+	Values []valueAnalysis
+}
+
+// A valueAnalysis is a constant of a type along with its JSON representation.
+type valueAnalysis struct {
+	parser.EnumValue
+	// JSONName is the string used for the constant in JSON. Unless given with
+	// a directive, it's the transformed name of the constant for integer types
+	// and its value for string types.
+	JSONName string
+	// QuotedJSON is the JSON encoding of the constant: either JSONName as a
+	// JSON string, or Number when integers are encoded as numbers.
+	QuotedJSON string
+	// Number is the decimal representation of the value of an integer
+	// constant.
+	Number string
+	// FromString is set when the name used in JSON is the result of calling
+	// String on the constant, in which case JSONName is empty.
+	FromString bool
+	// Emit is false for constants whose value was already declared by an
+	// earlier constant, so only the first name is used for encoding.
+	Emit bool
+	// Duplicate is set when JSONName is also the one of an earlier constant
+	// with the same value, as for aliased constants of string types, so it's
+	// only listed once for decoding.
+	Duplicate bool
+	// Bit is set, in -flags mode, for the emitted constants with a single
+	// bit set.
+	Bit bool
+}
+
+// generate returns the formatted source of a file containing the code for
+// all the given types.
+func (g *generator) generate(typeNames []string) ([]byte, error) {
+	var analysis = struct {
+		Command     string
+		PackageName string
+		Text        bool
+		SQL         bool
+		YAML        bool
+		XML         bool
+		Match       matchPolicy
+		Open        bool
+		Flags       bool
+		Types       []typeAnalysis
+	}{
+		Command:     g.command,
+		PackageName: g.pkg.Name,
+		Text:        g.text,
+		SQL:         g.sql,
+		YAML:        g.yaml,
+		XML:         g.xml,
+		Match:       g.match,
+		Open:        g.open,
+		Flags:       g.flags,
+	}
+	if g.flags && (g.intFormat || g.lenient || g.open || g.fallback != "" || g.sql || g.yaml || g.xml) {
+		return nil, fmt.Errorf("-flags can't be combined with -format=int, -lenient, -open, -fallback, -sql, -yaml or -xml")
+	}
+
+	for _, typeName := range typeNames {
+		typ, err := g.analyzeType(typeName)
 		if err != nil {
-			// Should never happen, but can arise when developing this code.
-			// The user can compile the output to see the error.
-			log.Printf("warning: internal error: invalid Go generated: %s", err)
-			log.Printf("warning: compile the package to analyze the error")
-			src = buf.Bytes()
+			return nil, err
 		}
+		analysis.Types = append(analysis.Types, typ)
+	}
+
+	var buf bytes.Buffer
+	if err := generatedTmpl.Execute(&buf, analysis); err != nil {
+		return nil, err
+	}
 
-		output := strings.ToLower(*outputPrefix + typeName +
-			*outputSuffix + ".go")
-		outputPath := filepath.Join(dir, output)
-		if err := ioutil.WriteFile(outputPath, src, 0644); err != nil {
-			log.Fatalf("writing output: %s", err)
+	src, err := formatSource(buf.Bytes())
+	if err != nil {
+		// Should never happen, but can arise when developing this code.
+		// The user can compile the output to see the error.
+		log.Printf("warning: internal error: invalid Go generated: %s", err)
+		log.Printf("warning: compile the package to analyze the error")
+		src = buf.Bytes()
+	}
+	return src, nil
+}
+
+// formatSource formats the generated code after removing the imports that
+// the enabled features don't use, so the template can list them all.
+func formatSource(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", src, goparser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var unused []*ast.ImportSpec
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !astutil.UsesImport(file, path) {
+			unused = append(unused, imp)
+		}
+	}
+	for _, imp := range unused {
+		// Named imports, such as yaml for gopkg.in/yaml.v3, are only deleted
+		// when given their name.
+		name := ""
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		path, _ := strconv.Unquote(imp.Path.Value)
+		astutil.DeleteNamedImport(fset, file, name, path)
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// analyzeType finds the constants of the named type and how they're
+// represented in JSON.
+func (g *generator) analyzeType(typeName string) (typeAnalysis, error) {
+	values, err := g.pkg.ValuesOfTypeDetailed(typeName)
+	if err != nil {
+		return typeAnalysis{}, fmt.Errorf("finding values for type %v: %v", typeName, err)
+	}
+	hasString, err := g.pkg.HasMethod(typeName, "String")
+	if err != nil {
+		return typeAnalysis{}, err
+	}
+	isStringer, err := g.pkg.IsStringer(typeName)
+	if err != nil {
+		return typeAnalysis{}, err
+	}
+	underlying, err := g.pkg.Underlying(typeName)
+	if err != nil {
+		return typeAnalysis{}, err
+	}
+	typ := typeAnalysis{
+		Name:           typeName,
+		IsString:       values[0].Value.Kind() == constant.String,
+		GenerateString: !hasString,
+		IsStringer:     isStringer,
+		Underlying:     underlying,
+	}
+	// String types are always encoded as strings. Unknown values of integer
+	// types are kept as numbers with -open, so numbers are accepted too.
+	typ.IntFormat = g.intFormat && !typ.IsString
+	typ.AcceptNames = !typ.IntFormat || g.lenient
+	typ.AcceptNumbers = (g.intFormat || g.lenient || g.open) && !typ.IsString
+	for _, v := range values {
+		if g.fallback == "" && v.Default || g.fallback == v.Name {
+			typ.Fallback = v.Name
 		}
 	}
+	if g.fallback != "" && typ.Fallback == "" {
+		return typeAnalysis{}, fmt.Errorf("fallback %s is not a constant of type %s", g.fallback, typeName)
+	}
+	if typ.IsString && g.flags {
+		return typeAnalysis{}, fmt.Errorf("type %s is a string, it can't hold bit flags", typeName)
+	}
+	if typ.Fallback != "" && g.flags {
+		return typeAnalysis{}, fmt.Errorf("type %s can't have a fallback when used as bit flags", typeName)
+	}
+	if typ.Fallback != "" && g.open {
+		return typeAnalysis{}, fmt.Errorf("type %s can't have a fallback when unknown values are kept", typeName)
+	}
+
+	// Every name accepted when decoding must identify a single value, and
+	// only the first constant for each value is used when encoding.
+	// Names must also be unique once normalized, unless they're for the same
+	// value. The names returned by String can only be checked at run time,
+	// where those that collide only match exactly.
+	type acceptedName struct{ name, key string }
+	accepted := make(map[string]acceptedName)
+	emitted := make(map[string]bool)
+	type normalizedName struct{ name, key string }
+	normalized := make(map[string]normalizedName)
+	// In -flags mode, the constants with a single bit set are the flags,
+	// and the others are only accepted as names when decoding.
+	mask := constant.MakeInt64(0)
+	for _, v := range values {
+		va := valueAnalysis{EnumValue: v, JSONName: v.JSONName}
+		switch {
+		case va.JSONName != "":
+		case isStringer:
+			va.FromString = true
+		case typ.IsString:
+			va.JSONName = constant.StringVal(v.Value)
+		default:
+			va.JSONName = g.jsonName(v.Name)
+		}
+
+		if !typ.IsString {
+			va.Number = v.Value.ExactString()
+		}
+		switch {
+		case typ.IntFormat:
+			va.QuotedJSON = va.Number
+		case !va.FromString:
+			quoted, err := json.Marshal(va.JSONName)
+			if err != nil {
+				return typeAnalysis{}, fmt.Errorf("encoding JSON name of %s: %v", v.Name, err)
+			}
+			va.QuotedJSON = string(quoted)
+		}
+
+		key := v.Value.ExactString()
+		names := v.Aliases
+		if !va.FromString {
+			names = append([]string{va.JSONName}, names...)
+		}
+		var aliases []string
+		for i, n := range names {
+			other, ok := accepted[n]
+			if ok && other.key != key {
+				return typeAnalysis{}, fmt.Errorf("%v: JSON name %q of %s is already used by %s",
+					v.Position, n, v.Name, other.name)
+			}
+			accepted[n] = acceptedName{name: v.Name, key: key}
+			switch {
+			case i == 0 && !va.FromString:
+				va.Duplicate = ok
+			case !ok:
+				aliases = append(aliases, n)
+			}
+		}
+		va.Aliases = aliases
+		if g.match.Normalize() {
+			for _, n := range names {
+				n = g.match.normalize(n)
+				if other, ok := normalized[n]; ok && other.key != key {
+					return typeAnalysis{}, fmt.Errorf("%v: JSON name of %s matches the one of %s once normalized as %q",
+						v.Position, v.Name, other.name, n)
+				}
+				normalized[n] = normalizedName{name: v.Name, key: key}
+			}
+		}
+
+		va.Emit = !emitted[key]
+		emitted[key] = true
+		if g.flags && va.Emit && isBit(v.Value) {
+			va.Bit = true
+			mask = constant.BinaryOp(mask, token.OR, v.Value)
+		}
+		typ.Values = append(typ.Values, va)
+	}
+	if g.flags {
+		if constant.Sign(mask) == 0 {
+			return typeAnalysis{}, fmt.Errorf("type %s has no constants with a single bit set", typeName)
+		}
+		typ.Mask = mask.ExactString()
+	}
+	return typ, nil
+}
+
+// isBit reports whether an integer constant has a single bit set.
+func isBit(v constant.Value) bool {
+	if constant.Sign(v) <= 0 {
+		return false
+	}
+	prev := constant.BinaryOp(v, token.SUB, constant.MakeInt64(1))
+	return constant.Sign(constant.BinaryOp(v, token.AND, prev)) == 0
+}
+
+// jsonName returns the JSON representation of the constant with the given
+// name, after trimming the prefix and applying the transform.
+func (g *generator) jsonName(name string) string {
+	name = strings.TrimPrefix(name, g.trimPrefix)
+	if g.transform == nil {
+		return name
+	}
+	return g.transform(name)
 }
@@ -0,0 +1,1523 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/campoy/jsonenums/parser"
+)
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+const clothesCode = `
+package clothes
+
+type ShirtSize byte
+
+const (
+	NA ShirtSize = iota
+	XS
+	S
+	M
+	L
+	XL
+)
+
+type WeekDay int
+
+const (
+	Monday WeekDay = iota
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+	Sunday
+)
+
+type Fabric string
+
+const (
+	Cotton Fabric = "cotton"
+	Wool   Fabric = "wool"
+	Silk   Fabric = "silk \"mulberry\""
+	// Lana is another name for Wool.
+	Lana Fabric = "wool"
+)
+
+type Pill int
+
+const (
+	Placebo     Pill = iota
+	Paracetamol      // json:"acetaminophen"
+	Ibuprofen        //jsonenums:name=advil
+	//jsonenums:alias=aspirin
+	Aspirin
+	Tylenol Pill = Paracetamol
+)
+
+func (d WeekDay) String() string {
+	switch d {
+	case Monday:
+		return "Dilluns"
+	case Tuesday:
+		return "Dimarts"
+	default:
+		return "Altres"
+	}
+}
+`
+
+const clothesTest = `
+package clothes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	type outfit struct {
+		Size   ShirtSize
+		Day    WeekDay
+		Fabric Fabric
+	}
+	in := outfit{XL, Tuesday, Silk}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), ` + "`" + `{"Size":"XL","Day":"Dimarts","Fabric":"silk \"mulberry\""}` + "`" + `; got != want {
+		t.Fatalf("expected %s; got %s", want, got)
+	}
+	var out outfit
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Fatalf("expected %v; got %v", in, out)
+	}
+	if err := json.Unmarshal([]byte(` + "`" + `{"Size":"XXL"}` + "`" + `), &out); err == nil {
+		t.Fatal("expected error decoding unknown size")
+	}
+	if err := json.Unmarshal([]byte(` + "`" + `{"Fabric":"Cotton"}` + "`" + `), &out); err == nil {
+		t.Fatal("expected error decoding unknown fabric")
+	}
+	if _, err := json.Marshal(Fabric("linen")); err == nil {
+		t.Fatal("expected error encoding invalid fabric")
+	}
+	if got := FabricValues(); len(got) != 3 || got[0] != Cotton || got[2] != Silk {
+		t.Fatalf("unexpected fabric values %v", got)
+	}
+
+	pills := map[string]Pill{
+		` + "`" + `"Placebo"` + "`" + `:       Placebo,
+		` + "`" + `"acetaminophen"` + "`" + `: Paracetamol,
+		` + "`" + `"Tylenol"` + "`" + `:       Tylenol,
+		` + "`" + `"advil"` + "`" + `:         Ibuprofen,
+		` + "`" + `"aspirin"` + "`" + `:       Aspirin,
+		` + "`" + `"Aspirin"` + "`" + `:       Aspirin,
+	}
+	for name, want := range pills {
+		var got Pill
+		if err := json.Unmarshal([]byte(name), &got); err != nil || got != want {
+			t.Errorf("decoding %s: expected %v; got %v (%v)", name, want, got, err)
+		}
+	}
+	for _, name := range []string{` + "`" + `"Paracetamol"` + "`" + `, ` + "`" + `"Ibuprofen"` + "`" + `} {
+		var got Pill
+		if err := json.Unmarshal([]byte(name), &got); err == nil {
+			t.Errorf("decoding %s: expected error", name)
+		}
+	}
+	if b, err := json.Marshal(Tylenol); err != nil || string(b) != ` + "`" + `"acetaminophen"` + "`" + ` {
+		t.Errorf("encoding Tylenol: got %s (%v)", b, err)
+	}
+	if got := PillValues(); len(got) != 4 {
+		t.Errorf("unexpected pill values %v", got)
+	}
+}
+
+var (
+	sizeJSON = []byte(` + "`" + `"XL"` + "`" + `)
+	dayJSON  = []byte(` + "`" + `"Dimarts"` + "`" + `)
+)
+
+func TestAllocs(t *testing.T) {
+	var (
+		size ShirtSize
+		day  WeekDay
+	)
+	// Encoding only allocates the returned slice.
+	tests := map[string]struct {
+		f      func()
+		allocs float64
+	}{
+		"ShirtSize.MarshalJSON":   {func() { XL.MarshalJSON() }, 1},
+		"ShirtSize.UnmarshalJSON": {func() { size.UnmarshalJSON(sizeJSON) }, 0},
+		"WeekDay.MarshalJSON":     {func() { Tuesday.MarshalJSON() }, 1},
+		"WeekDay.UnmarshalJSON":   {func() { day.UnmarshalJSON(dayJSON) }, 0},
+	}
+	for name, tt := range tests {
+		if n := testing.AllocsPerRun(100, tt.f); n != tt.allocs {
+			t.Errorf("%s: expected %v allocations; got %v", name, tt.allocs, n)
+		}
+	}
+}
+
+func TestMarshalJSONCopies(t *testing.T) {
+	b, err := S.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[1] = 'Z'
+	if b, err := json.Marshal(S); err != nil || string(b) != ` + "`" + `"S"` + "`" + ` {
+		t.Errorf("expected S to be encoded as \"S\" after modifying an earlier encoding; got %s (%v)", b, err)
+	}
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		XL.MarshalJSON()
+	}
+}
+
+// BenchmarkMarshalJSONName encodes the name every time, as the generated
+// code used to do.
+func BenchmarkMarshalJSONName(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		json.Marshal(_ShirtSizeValueToName[XL])
+	}
+}
+
+func BenchmarkUnmarshalJSON(b *testing.B) {
+	b.ReportAllocs()
+	var size ShirtSize
+	for i := 0; i < b.N; i++ {
+		size.UnmarshalJSON(sizeJSON)
+	}
+}
+
+// BenchmarkUnmarshalJSONString decodes the name before looking it up, as the
+// generated code used to do.
+func BenchmarkUnmarshalJSONString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var s string
+		json.Unmarshal(sizeJSON, &s)
+		_ = _ShirtSizeNameToValue[s]
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var size ShirtSize
+	err := json.Unmarshal([]byte(` + "`" + `"XXL"` + "`" + `), &size)
+	var sizeErr *InvalidShirtSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("expected an InvalidShirtSizeError; got %v", err)
+	}
+	if sizeErr.Type != "ShirtSize" || sizeErr.Input != ` + "`" + `"XXL"` + "`" + ` {
+		t.Errorf("unexpected error %+v", sizeErr)
+	}
+	if want := []string{"NA", "XS", "S", "M", "L", "XL"}; !reflect.DeepEqual(sizeErr.Valid, want) {
+		t.Errorf("expected valid names %v; got %v", want, sizeErr.Valid)
+	}
+	sizeErr.Valid[0] = "changed"
+	if _, err := json.Marshal(NA); err != nil {
+		t.Errorf("encoding NA after changing the valid names: %v", err)
+	}
+
+	_, err = json.Marshal(WeekDay(9))
+	var dayErr *InvalidWeekDayError
+	if !errors.As(err, &dayErr) {
+		t.Fatalf("expected an InvalidWeekDayError; got %v", err)
+	}
+	if dayErr.Input != "9" || len(dayErr.Valid) != 7 || dayErr.Valid[1] != "Dimarts" {
+		t.Errorf("unexpected error %+v", dayErr)
+	}
+
+	var pill Pill
+	err = json.Unmarshal([]byte("true"), &pill)
+	var pillErr *InvalidPillError
+	if !errors.As(err, &pillErr) {
+		t.Fatalf("expected an InvalidPillError; got %v", err)
+	}
+	if want := []string{"Placebo", "acetaminophen", "advil", "Aspirin"}; !reflect.DeepEqual(pillErr.Valid, want) {
+		t.Errorf("expected valid names %v; got %v", want, pillErr.Valid)
+	}
+	if got, want := err.Error(), "invalid Pill true: should be a string"; got != want {
+		t.Errorf("expected error %q; got %q", want, got)
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	values := ShirtSizeValues()
+	if want := []ShirtSize{NA, XS, S, M, L, XL}; !reflect.DeepEqual(values, want) {
+		t.Errorf("expected values %v; got %v", want, values)
+	}
+	values[0] = XL
+	if got := ShirtSizeValues()[0]; got != NA {
+		t.Errorf("expected ShirtSizeValues to return a copy; got %v first", got)
+	}
+	names := PillNames()
+	if want := []string{"Placebo", "acetaminophen", "advil", "Aspirin"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected names %v; got %v", want, names)
+	}
+	names[0] = "Sugar"
+	if got := PillNames()[0]; got != "Placebo" {
+		t.Errorf("expected PillNames to return a copy; got %v first", got)
+	}
+	if got := WeekDayNames(); len(got) != 7 || got[0] != "Dilluns" {
+		t.Errorf("unexpected week day names %v", got)
+	}
+
+	for v, want := range map[fmt.Stringer]bool{XL: true, ShirtSize(42): false, Silk: true, Fabric("linen"): false} {
+		if got := v.(interface{ IsValid() bool }).IsValid(); got != want {
+			t.Errorf("expected IsValid of %v to be %v; got %v", v, want, got)
+		}
+	}
+
+	for name, want := range map[string]Pill{"acetaminophen": Paracetamol, "aspirin": Aspirin, "Aspirin": Aspirin} {
+		if got, err := ParsePill(name); err != nil || got != want {
+			t.Errorf("parsing %s: expected %v; got %v (%v)", name, want, got, err)
+		}
+	}
+	var pillErr *InvalidPillError
+	if _, err := ParsePill("Paracetamol"); !errors.As(err, &pillErr) || pillErr.Input != "Paracetamol" {
+		t.Errorf("parsing Paracetamol: expected an InvalidPillError; got %v", err)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   fmt.Stringer
+		want string
+	}{
+		{XL, "XL"},
+		{ShirtSize(42), "ShirtSize(42)"},
+		{Tuesday, "Dimarts"},
+		{Silk, ` + "`" + `silk "mulberry"` + "`" + `},
+		{Fabric("linen"), ` + "`" + `Fabric("linen")` + "`" + `},
+		{Tylenol, "acetaminophen"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("expected %s; got %s", tt.want, got)
+		}
+	}
+}
+`
+
+// writeModule creates a new module containing the given files, keyed by
+// slash-separated paths, and returns its directory. A go.mod file is added
+// unless one is given. The environment is set so that no workspace applies to
+// the module, and so that go.sum is updated as needed for the dependencies of
+// the generated code.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOWORK", "")
+	dir := t.TempDir()
+	if _, ok := files["go.mod"]; !ok {
+		files["go.mod"] = "module example.com/gen\n\ngo 1.18\n"
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		must(t, os.MkdirAll(filepath.Dir(path), 0755))
+		must(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+// parseModule creates a new module with the given files, see writeModule, and
+// returns the package at its root with its directory.
+func parseModule(t *testing.T, files map[string]string) (*parser.Package, string) {
+	t.Helper()
+	dir := writeModule(t, files)
+	pkg, err := parser.ParsePackage(dir)
+	if err != nil {
+		t.Fatalf("parse package: %v", err)
+	}
+	return pkg, dir
+}
+
+// runGo runs the go command with the given arguments in dir and fails the
+// test if it doesn't succeed.
+func runGo(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+const clothesTextTest = `
+package clothes
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTextKeys(t *testing.T) {
+	in := map[ShirtSize]Pill{S: Aspirin, XL: Tylenol}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), ` + "`" + `{"S":"Aspirin","XL":"acetaminophen"}` + "`" + `; got != want {
+		t.Fatalf("expected %s; got %s", want, got)
+	}
+	var out map[ShirtSize]Pill
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[S] != Aspirin || out[XL] != Paracetamol {
+		t.Fatalf("expected %v; got %v", in, out)
+	}
+	if err := new(ShirtSize).UnmarshalText([]byte("XXL")); err == nil {
+		t.Fatal("expected error decoding unknown size")
+	}
+}
+`
+
+const clothesSQLTest = `
+package clothes
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// fakeDriver is a database/sql driver whose statements store the arguments
+// they're executed with, and return their arguments as a single row when
+// queried.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("transactions not supported") }
+
+var stored []driver.Value
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	stored = args
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{row: args}, nil
+}
+
+type fakeRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *fakeRows) Columns() []string { return make([]string, len(r.row)) }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+func init() { sql.Register("fake", fakeDriver{}) }
+
+func TestSQL(t *testing.T) {
+	db, err := sql.Open("fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("insert", XL, Tuesday, Silk, Tylenol); err != nil {
+		t.Fatalf("inserting: %v", err)
+	}
+	if want := []driver.Value{"XL", "Dimarts", ` + "`" + `silk "mulberry"` + "`" + `, "acetaminophen"}; !reflect.DeepEqual(stored, want) {
+		t.Errorf("expected to store %q; got %q", want, stored)
+	}
+	if _, err := db.Exec("insert", ShirtSize(42)); err == nil {
+		t.Errorf("expected error storing an invalid size")
+	}
+
+	var (
+		size   ShirtSize
+		day    WeekDay
+		fabric Fabric
+		pill   Pill
+	)
+	err = db.QueryRow("select", "M", []byte("Dilluns"), []byte("wool"), int64(3)).Scan(&size, &day, &fabric, &pill)
+	if err != nil {
+		t.Fatalf("scanning: %v", err)
+	}
+	if size != M || day != Monday || fabric != Wool || pill != Aspirin {
+		t.Errorf("expected M, Monday, Wool and Aspirin; got %v, %v, %v and %v", size, day, fabric, pill)
+	}
+	if err := db.QueryRow("select", "2").Scan(&size); err != nil || size != S {
+		t.Errorf("scanning a string of digits: expected S; got %v (%v)", size, err)
+	}
+
+	for _, src := range []interface{}{"XXL", int64(42), int64(256), -1.5, nil} {
+		err := db.QueryRow("select", src).Scan(&size)
+		var sizeErr *InvalidShirtSizeError
+		if !errors.As(err, &sizeErr) {
+			t.Errorf("scanning %v: expected an InvalidShirtSizeError; got %v", src, err)
+		}
+	}
+	if err := db.QueryRow("select", int64(1)).Scan(&fabric); err == nil {
+		t.Errorf("expected error scanning an integer into a fabric")
+	}
+}
+`
+
+const clothesXMLTest = `
+package clothes
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+)
+
+type outfit struct {
+	Size   ShirtSize ` + "`" + `xml:"size,attr"` + "`" + `
+	Day    WeekDay   ` + "`" + `xml:"day,attr"` + "`" + `
+	Fabric Fabric
+	Pill   Pill
+}
+
+func TestXML(t *testing.T) {
+	in := outfit{XL, Tuesday, Silk, Tylenol}
+	b, err := xml.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ` + "`" + `<outfit size="XL" day="Dimarts"><Fabric>silk &#34;mulberry&#34;</Fabric><Pill>acetaminophen</Pill></outfit>` + "`" + `
+	if string(b) != want {
+		t.Fatalf("expected %s; got %s", want, b)
+	}
+	var out outfit
+	if err := xml.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Fatalf("expected %v; got %v", in, out)
+	}
+
+	var sizeErr *InvalidShirtSizeError
+	if err := xml.Unmarshal([]byte(` + "`" + `<outfit size="XXL"></outfit>` + "`" + `), &out); !errors.As(err, &sizeErr) {
+		t.Errorf("expected an InvalidShirtSizeError decoding an attribute; got %v", err)
+	}
+	var pillErr *InvalidPillError
+	if err := xml.Unmarshal([]byte(` + "`" + `<outfit><Pill>Paracetamol</Pill></outfit>` + "`" + `), &out); !errors.As(err, &pillErr) {
+		t.Errorf("expected an InvalidPillError decoding an element; got %v", err)
+	}
+	if _, err := xml.Marshal(outfit{Size: 42}); !errors.As(err, &sizeErr) {
+		t.Errorf("expected an InvalidShirtSizeError encoding an attribute; got %v", err)
+	}
+	if _, err := xml.Marshal(outfit{Fabric: "linen"}); err == nil {
+		t.Errorf("expected error encoding an element")
+	}
+}
+`
+
+// clothesTypes are the types defined in clothesCode.
+var clothesTypes = []string{"ShirtSize", "WeekDay", "Fabric", "Pill"}
+
+func TestGenerateCompiles(t *testing.T) {
+	tests := []struct {
+		name string
+		// perType generates a file per type rather than a single one.
+		perType bool
+		// options are copied into the generator.
+		options generator
+		// tests are extra test files for the generated package.
+		tests map[string]string
+	}{
+		{name: "file per type", perType: true},
+		{name: "single output"},
+		{
+			name:    "text",
+			options: generator{text: true},
+			tests:   map[string]string{"text_test.go": clothesTextTest},
+		},
+		{
+			name:    "sql",
+			options: generator{sql: true},
+			tests:   map[string]string{"sql_test.go": clothesSQLTest},
+		},
+		{
+			name:    "xml",
+			options: generator{xml: true},
+			tests:   map[string]string{"xml_test.go": clothesXMLTest},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{
+				"clothes.go":      clothesCode,
+				"clothes_test.go": clothesTest,
+			}
+			for name, src := range tt.tests {
+				files[name] = src
+			}
+			outputs := map[string][]string{"clothes_jsonenums.go": clothesTypes}
+			if tt.perType {
+				outputs = make(map[string][]string)
+				for _, typ := range clothesTypes {
+					outputs[strings.ToLower(typ)+"_jsonenums.go"] = []string{typ}
+				}
+			}
+			testGenerated(t, tt.options, files, outputs)
+		})
+	}
+}
+
+// testGenerated creates a module with the given files, generates the outputs
+// for the listed types with a copy of the given generator, and then vets and
+// tests the resulting package.
+func testGenerated(t *testing.T, options generator, files map[string]string, outputs map[string][]string) {
+	pkg, dir := parseModule(t, files)
+	g := options
+	g.pkg = pkg
+	for name, types := range outputs {
+		g.command = "-type=" + strings.Join(types, ",")
+		src, err := g.generate(types)
+		must(t, err)
+		must(t, ioutil.WriteFile(filepath.Join(dir, name), src, 0644))
+	}
+	runGo(t, dir, "vet", ".")
+	runGo(t, dir, "test", "-bench=.", "-benchtime=1x", ".")
+}
+
+const levelCode = `
+package level
+
+type Level uint8
+
+const (
+	Debug Level = iota + 1
+	Info
+	Warn
+	Error
+	Fatal Level = 200
+)
+
+type Signed int
+
+const (
+	Minus Signed = -1
+	Zero  Signed = 0
+)
+
+type Named int
+
+const (
+	First  Named = 10
+	Second Named = 20
+)
+
+func (n Named) String() string {
+	if n == First {
+		return "first"
+	}
+	return "second"
+}
+`
+
+const levelIntTest = `
+package level
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+)
+
+func TestIntFormat(t *testing.T) {
+	type config struct {
+		Level  Level
+		Signed Signed
+		Named  Named
+	}
+	in := config{Warn, Minus, Second}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), ` + "`" + `{"Level":3,"Signed":-1,"Named":20}` + "`" + `; got != want {
+		t.Fatalf("expected %s; got %s", want, got)
+	}
+	var out config
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Fatalf("expected %v; got %v", in, out)
+	}
+	for _, bad := range []string{"0", "5", "259", ` + "`" + `"Warn"` + "`" + `, "3.5"} {
+		if err := json.Unmarshal([]byte(bad), &out.Level); err == nil {
+			t.Errorf("expected error decoding %s", bad)
+		}
+	}
+	if _, err := json.Marshal(Level(5)); err == nil {
+		t.Errorf("expected error encoding invalid level")
+	}
+	if got := Warn.String(); got != "Warn" {
+		t.Errorf("expected String to return the name; got %s", got)
+	}
+
+	type element struct {
+		Level  Level  ` + "`" + `xml:"level,attr"` + "`" + `
+		Signed Signed
+	}
+	b, err = xml.Marshal(element{Warn, Minus})
+	if got, want := string(b), ` + "`" + `<element level="3"><Signed>-1</Signed></element>` + "`" + `; err != nil || got != want {
+		t.Fatalf("expected %s; got %s (%v)", want, got, err)
+	}
+	var e element
+	if err := xml.Unmarshal(b, &e); err != nil || e.Level != Warn || e.Signed != Minus {
+		t.Errorf("decoding %s: got %+v (%v)", b, e, err)
+	}
+	if err := xml.Unmarshal([]byte(` + "`" + `<element level="Warn"></element>` + "`" + `), &e); err == nil {
+		t.Errorf("expected error decoding a name")
+	}
+}
+`
+
+const levelLenientTest = `
+package level
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLenient(t *testing.T) {
+	for in, want := range map[string]Level{` + "`" + `"Warn"` + "`" + `: Warn, "3": Warn, "200": Fatal, ` + "`" + `"Fatal"` + "`" + `: Fatal} {
+		var got Level
+		if err := json.Unmarshal([]byte(in), &got); err != nil || got != want {
+			t.Errorf("decoding %s: expected %v; got %v (%v)", in, want, got, err)
+		}
+	}
+	for in, msg := range map[string]string{
+		"7":                           "not the value of any constant",
+		` + "`" + `"Trace"` + "`" + `: "not the name of any constant",
+		"true":                        "should be a string or a number",
+	} {
+		var got Level
+		err := json.Unmarshal([]byte(in), &got)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("decoding %s: expected error containing %q; got %v", in, msg, err)
+		}
+	}
+}
+`
+
+func TestGenerateLenient(t *testing.T) {
+	// String types are generated along with the others, ignoring the flags.
+	const modeCode = "package level\n\ntype Mode string\n\nconst Strict Mode = \"strict\"\n"
+	for _, intFormat := range []bool{false, true} {
+		testGenerated(t, generator{intFormat: intFormat, lenient: true, xml: true},
+			map[string]string{"level.go": levelCode, "mode.go": modeCode, "level_test.go": levelLenientTest},
+			map[string][]string{"level_jsonenums.go": {"Level", "Signed", "Named", "Mode"}})
+	}
+}
+
+const statusCode = `package status
+
+type Status int
+
+const (
+	StatusTodo Status = iota
+	StatusInProgress
+	StatusDone
+)
+`
+
+const statusMatchTest = `
+package status
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	for in, want := range map[string]Status{
+		` + "`" + `"in_progress"` + "`" + `:   StatusInProgress,
+		` + "`" + `"In-Progress"` + "`" + `:   StatusInProgress,
+		` + "`" + `" DONE\t"` + "`" + `:      StatusDone,
+		` + "`" + `"todo"` + "`" + `:          StatusTodo,
+	} {
+		var got Status
+		if err := json.Unmarshal([]byte(in), &got); err != nil || got != want {
+			t.Errorf("decoding %s: expected %v; got %v (%v)", in, want, got, err)
+		}
+		b, err := json.Marshal(got)
+		if err != nil || string(b) != ` + "`" + `"` + "`" + `+_StatusValueToName[want]+` + "`" + `"` + "`" + ` {
+			t.Errorf("encoding %v: expected canonical name; got %s (%v)", want, b, err)
+		}
+	}
+	var got Status
+	if err := got.UnmarshalText([]byte("IN-PROGRESS")); err != nil || got != StatusInProgress {
+		t.Errorf("decoding text: expected %v; got %v (%v)", StatusInProgress, got, err)
+	}
+	if got, err := ParseStatus(" Done"); err != nil || got != StatusDone {
+		t.Errorf("parsing: expected %v; got %v (%v)", StatusDone, got, err)
+	}
+	if err := json.Unmarshal([]byte(` + "`" + `"inprogress"` + "`" + `), &got); err == nil {
+		t.Errorf("expected error decoding a name without separators")
+	}
+}
+`
+
+func TestGenerateMatch(t *testing.T) {
+	m, err := parseMatchPolicy("fold,trim,separators")
+	must(t, err)
+	testGenerated(t, generator{transform: transforms["snake"], trimPrefix: "Status", text: true, match: m},
+		map[string]string{"status.go": statusCode, "status_test.go": statusMatchTest},
+		map[string][]string{"status_jsonenums.go": {"Status"}})
+
+	// Exact matching is the default.
+	pkg, _ := parseModule(t, map[string]string{"status.go": statusCode})
+	g := &generator{pkg: pkg, transform: transforms["snake"], trimPrefix: "Status"}
+	src, err := g.generate([]string{"Status"})
+	must(t, err)
+	if strings.Contains(string(src), "Normalize") {
+		t.Errorf("expected no normalization by default; got\n%s", src)
+	}
+
+	// Names can't collide once normalized.
+	g = &generator{pkg: pkg, match: matchPolicy{Fold: true}}
+	g.pkg, _ = parseModule(t, map[string]string{"status.go": `package status
+
+type Status int
+
+const (
+	Done Status = iota
+	DONE
+)
+`})
+	if _, err := g.generate([]string{"Status"}); err == nil || !strings.Contains(err.Error(), "once normalized") {
+		t.Errorf("expected error for names colliding once normalized; got %v", err)
+	}
+
+	// The names returned by String that collide once normalized only match
+	// exactly.
+	testGenerated(t, generator{match: matchPolicy{Fold: true}},
+		map[string]string{"status.go": statusStringerCode, "status_test.go": statusStringerTest},
+		map[string][]string{"status_jsonenums.go": {"Status"}})
+}
+
+const statusStringerCode = `
+package status
+
+type Status int
+
+const (
+	Done Status = iota
+	Finished
+	Pending
+)
+
+func (s Status) String() string {
+	switch s {
+	case Done:
+		return "Done"
+	case Finished:
+		return "DONE"
+	}
+	return "Pending"
+}
+`
+
+const statusStringerTest = `
+package status
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAmbiguous(t *testing.T) {
+	for in, want := range map[string]Status{` + "`" + `"Done"` + "`" + `: Done, ` + "`" + `"DONE"` + "`" + `: Finished, ` + "`" + `"pending"` + "`" + `: Pending} {
+		var got Status
+		if err := json.Unmarshal([]byte(in), &got); err != nil || got != want {
+			t.Errorf("decoding %s: expected %v; got %v (%v)", in, want, got, err)
+		}
+	}
+	var got Status
+	if err := json.Unmarshal([]byte(` + "`" + `"done"` + "`" + `), &got); err == nil {
+		t.Errorf("decoding \"done\": expected error; got %v", got)
+	}
+}
+`
+
+func TestParseMatchPolicy(t *testing.T) {
+	for in, want := range map[string]matchPolicy{
+		"":                     {},
+		"exact":                {},
+		"fold":                 {Fold: true},
+		"trim, separators":     {Trim: true, Separators: true},
+		"fold,trim,separators": {Fold: true, Trim: true, Separators: true},
+	} {
+		got, err := parseMatchPolicy(in)
+		if err != nil || got != want {
+			t.Errorf("parseMatchPolicy(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+	if _, err := parseMatchPolicy("fold,case"); err == nil {
+		t.Errorf("expected error for unknown match option")
+	}
+}
+
+const openCode = `
+package open
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+
+type Shape string
+
+const (
+	Circle Shape = "circle"
+	Square Shape = "square"
+)
+`
+
+const openTest = `
+package open
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	var colors []OpenColor
+	in := ` + "`" + `["Green","Blue"]` + "`" + `
+	if err := json.Unmarshal([]byte(in), &colors); err != nil {
+		t.Fatalf("decoding %s: %v", in, err)
+	}
+	if colors[0].Value != Green || colors[0].Unknown != nil {
+		t.Errorf("expected Green to be known; got %+v", colors[0])
+	}
+	if string(colors[1].Unknown) != ` + "`" + `"Blue"` + "`" + ` {
+		t.Errorf("expected Blue to be unknown; got %+v", colors[1])
+	}
+	if b, err := json.Marshal(colors); err != nil || string(b) != in {
+		t.Errorf("encoding %+v: expected %s; got %s (%v)", colors, in, b, err)
+	}
+	// Unknown numbers are kept in the value itself.
+	var color OpenColor
+	if err := json.Unmarshal([]byte("3"), &color); err != nil || color.Value != 3 || color.Unknown != nil {
+		t.Errorf("decoding 3: got %+v (%v)", color, err)
+	}
+	if b, err := json.Marshal(Color(9)); err != nil || string(b) != "9" {
+		t.Errorf("encoding Color(9): got %s (%v)", b, err)
+	}
+	var c Color
+	if err := json.Unmarshal([]byte("9"), &c); err != nil || c != 9 {
+		t.Errorf("decoding 9: got %v (%v)", c, err)
+	}
+	if err := json.Unmarshal([]byte(` + "`" + `"Blue"` + "`" + `), &c); err == nil {
+		t.Errorf("expected error decoding an unknown name as a Color")
+	}
+
+	var s Shape
+	if err := json.Unmarshal([]byte(` + "`" + `"triangle"` + "`" + `), &s); err != nil || s != "triangle" {
+		t.Errorf("decoding triangle: got %q (%v)", s, err)
+	}
+	if b, err := json.Marshal(s); err != nil || string(b) != ` + "`" + `"triangle"` + "`" + ` {
+		t.Errorf("encoding %q: got %s (%v)", s, b, err)
+	}
+	if err := s.UnmarshalText([]byte("hexagon")); err != nil || s != "hexagon" {
+		t.Errorf("decoding text hexagon: got %q (%v)", s, err)
+	}
+	if b, err := s.MarshalText(); err != nil || string(b) != "hexagon" {
+		t.Errorf("encoding text %q: got %s (%v)", s, b, err)
+	}
+}
+`
+
+const openIntTest = `
+package open
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	var c Color
+	if err := json.Unmarshal([]byte("7"), &c); err != nil || c != 7 {
+		t.Errorf("decoding 7: got %v (%v)", c, err)
+	}
+	if b, err := json.Marshal(c); err != nil || string(b) != "7" {
+		t.Errorf("encoding %v: got %s (%v)", c, b, err)
+	}
+	for _, in := range []string{"true", "1.5"} {
+		if err := json.Unmarshal([]byte(in), &c); err == nil {
+			t.Errorf("expected error decoding %s", in)
+		}
+	}
+
+	// Unknown values are kept as map keys too.
+	if b, err := json.Marshal(map[Color]int{9: 1}); err != nil || string(b) != ` + "`" + `{"9":1}` + "`" + ` {
+		t.Errorf("encoding map key Color(9): got %s (%v)", b, err)
+	}
+	var m map[Color]int
+	if err := json.Unmarshal([]byte(` + "`" + `{"9":1,"Green":2}` + "`" + `), &m); err != nil || m[9] != 1 || m[Green] != 2 {
+		t.Errorf("decoding map keys: got %v (%v)", m, err)
+	}
+
+	if v, err := Color(7).Value(); err != nil || v != int64(7) {
+		t.Errorf("storing Color(7): got %v (%v)", v, err)
+	}
+	for src, want := range map[interface{}]Color{int64(9): 9, "9": 9, "Green": Green} {
+		if err := c.Scan(src); err != nil || c != want {
+			t.Errorf("scanning %v: expected %v; got %v (%v)", src, want, c, err)
+		}
+	}
+}
+`
+
+func TestGenerateOpen(t *testing.T) {
+	testGenerated(t, generator{open: true, text: true},
+		map[string]string{"open.go": openCode, "open_test.go": openTest},
+		map[string][]string{"open_jsonenums.go": {"Color", "Shape"}})
+	for _, intFormat := range []bool{false, true} {
+		testGenerated(t, generator{open: true, intFormat: intFormat, lenient: !intFormat, text: true, sql: true},
+			map[string]string{"open.go": openCode, "open_test.go": openIntTest},
+			map[string][]string{"open_jsonenums.go": {"Color"}})
+	}
+}
+
+const fallbackCode = `
+package fallback
+
+type Size int
+
+const (
+	//jsonenums:default
+	NA Size = iota
+	S
+	M
+)
+
+type Kind string
+
+const (
+	Fruit Kind = "fruit"
+	Other Kind = "other" //jsonenums:default
+)
+`
+
+const fallbackTest = `
+package fallback
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	var s Size
+	for in, want := range map[string]Size{` + "`" + `"M"` + "`" + `: M, ` + "`" + `"XL"` + "`" + `: NA, ` + "`" + `"m"` + "`" + `: NA} {
+		if err := json.Unmarshal([]byte(in), &s); err != nil || s != want {
+			t.Errorf("decoding %s: expected %v; got %v (%v)", in, want, s, err)
+		}
+	}
+	if err := s.UnmarshalText([]byte("XL")); err != nil || s != NA {
+		t.Errorf("decoding text XL: expected NA; got %v (%v)", s, err)
+	}
+	if err := json.Unmarshal([]byte("true"), &s); err == nil {
+		t.Errorf("expected error decoding a boolean")
+	}
+	var k Kind
+	if err := json.Unmarshal([]byte(` + "`" + `"vegetable"` + "`" + `), &k); err != nil || k != Other {
+		t.Errorf("decoding vegetable: expected Other; got %v (%v)", k, err)
+	}
+}
+`
+
+const fallbackIntTest = `
+package fallback
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	var s Size
+	for in, want := range map[string]Size{"2": M, "7": S, "-1": S} {
+		if err := json.Unmarshal([]byte(in), &s); err != nil || s != want {
+			t.Errorf("decoding %s: expected %v; got %v (%v)", in, want, s, err)
+		}
+	}
+	// Only numbers fall back.
+	for _, in := range []string{` + "`" + `"M"` + "`" + `, "true"} {
+		if err := json.Unmarshal([]byte(in), &s); err == nil {
+			t.Errorf("decoding %s: expected error", in)
+		}
+	}
+}
+`
+
+func TestGenerateFallback(t *testing.T) {
+	testGenerated(t, generator{text: true},
+		map[string]string{"fallback.go": fallbackCode, "fallback_test.go": fallbackTest},
+		map[string][]string{"fallback_jsonenums.go": {"Size", "Kind"}})
+	// The flag overrides the directive.
+	testGenerated(t, generator{intFormat: true, fallback: "S"},
+		map[string]string{"fallback.go": fallbackCode, "fallback_test.go": fallbackIntTest},
+		map[string][]string{"fallback_jsonenums.go": {"Size"}})
+
+	pkg, _ := parseModule(t, map[string]string{"fallback.go": fallbackCode})
+	for _, g := range []*generator{
+		{pkg: pkg, fallback: "Other"},
+		{pkg: pkg, fallback: "L"},
+		{pkg: pkg, open: true},
+	} {
+		if _, err := g.generate([]string{"Size"}); err == nil {
+			t.Errorf("expected error generating Size with fallback %q and open %v", g.fallback, g.open)
+		}
+	}
+}
+
+const clothesYAMLTest = `
+package clothes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAML(t *testing.T) {
+	type outfit struct {
+		Size   ShirtSize
+		Day    WeekDay
+		Fabric Fabric
+		Pill   Pill
+	}
+	in := outfit{XL, Tuesday, Silk, Tylenol}
+	b, err := yaml.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"size: XL\n", "day: Dimarts\n", "pill: acetaminophen\n"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected YAML to contain %q; got\n%s", want, b)
+		}
+	}
+	var out outfit
+	if err := yaml.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Fatalf("expected %v; got %v", in, out)
+	}
+
+	if err := yaml.Unmarshal([]byte("pill: aspirin\n"), &out); err != nil || out.Pill != Aspirin {
+		t.Errorf("decoding alias: expected Aspirin; got %v (%v)", out.Pill, err)
+	}
+	var sizeErr *InvalidShirtSizeError
+	for _, doc := range []string{"size: XXL\n", "size: [XL]\n"} {
+		if err := yaml.Unmarshal([]byte(doc), &out); !errors.As(err, &sizeErr) {
+			t.Errorf("decoding %q: expected an InvalidShirtSizeError; got %v", doc, err)
+		}
+	}
+	if _, err := yaml.Marshal(outfit{Size: 42}); err == nil {
+		t.Errorf("expected error encoding an invalid size")
+	}
+}
+`
+
+const levelYAMLTest = `
+package level
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAML(t *testing.T) {
+	for in, want := range map[string]Level{"Warn": Warn, "3": Warn, "200": Fatal} {
+		var got Level
+		if err := yaml.Unmarshal([]byte(in), &got); err != nil || got != want {
+			t.Errorf("decoding %s: expected %v; got %v (%v)", in, want, got, err)
+		}
+	}
+	for _, in := range []string{"7", "300", "Trace", "'200'"} {
+		var got Level
+		if err := yaml.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("decoding %s: expected error", in)
+		}
+	}
+	if b, err := yaml.Marshal(Warn); err != nil || string(b) != "Warn\n" {
+		t.Errorf("encoding Warn: got %q (%v)", b, err)
+	}
+}
+`
+
+func TestGenerateYAML(t *testing.T) {
+	// The generated code depends on gopkg.in/yaml.v3, which must be
+	// available.
+	if out, err := exec.Command("go", "mod", "download", "gopkg.in/yaml.v3@v3.0.1").CombinedOutput(); err != nil {
+		t.Skipf("gopkg.in/yaml.v3 is not available: %v\n%s", err, out)
+	}
+	const goMod = "module example.com/gen\n\ngo 1.18\n\nrequire gopkg.in/yaml.v3 v3.0.1\n"
+	testGenerated(t, generator{yaml: true},
+		map[string]string{"go.mod": goMod, "clothes.go": clothesCode, "clothes_test.go": clothesYAMLTest},
+		map[string][]string{"clothes_jsonenums.go": clothesTypes})
+	testGenerated(t, generator{yaml: true, lenient: true},
+		map[string]string{"go.mod": goMod, "level.go": levelCode, "level_test.go": levelYAMLTest},
+		map[string][]string{"level_jsonenums.go": {"Level", "Signed", "Named"}})
+}
+
+const permissionCode = `
+package permission
+
+type Permission uint32
+
+const (
+	None Permission = 0
+	Read Permission = 1 << iota
+	Write
+	Execute
+	ReadWrite Permission = Read | Write
+)
+`
+
+const permissionTest = `
+package permission
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	for in, want := range map[Permission]string{
+		0:                  "[]",
+		Read:               ` + "`" + `["Read"]` + "`" + `,
+		Read | Write:       ` + "`" + `["Read","Write"]` + "`" + `,
+		Execute | Read:     ` + "`" + `["Read","Execute"]` + "`" + `,
+		ReadWrite | Execute: ` + "`" + `["Read","Write","Execute"]` + "`" + `,
+	} {
+		b, err := json.Marshal(in)
+		if err != nil || string(b) != want {
+			t.Errorf("encoding %d: expected %s; got %s (%v)", in, want, b, err)
+		}
+		var out Permission
+		if err := json.Unmarshal(b, &out); err != nil || out != in {
+			t.Errorf("decoding %s: expected %d; got %d (%v)", b, in, out, err)
+		}
+	}
+	var p Permission
+	if err := json.Unmarshal([]byte(` + "`" + `["ReadWrite","None","Execute"]` + "`" + `), &p); err != nil || p != ReadWrite|Execute {
+		t.Errorf("decoding composite names: got %d (%v)", p, err)
+	}
+	for _, in := range []string{` + "`" + `["Delete"]` + "`" + `, ` + "`" + `"Read"` + "`" + `, "3"} {
+		if err := json.Unmarshal([]byte(in), &p); err == nil {
+			t.Errorf("decoding %s: expected error", in)
+		}
+	}
+	var permErr *InvalidPermissionError
+	if _, err := json.Marshal(Read | 16); !errors.As(err, &permErr) {
+		t.Errorf("encoding unknown bits: expected an InvalidPermissionError; got %v", err)
+	}
+
+	b, err := json.Marshal(map[Permission]bool{Read | Execute: true})
+	if want := ` + "`" + `{"Read|Execute":true}` + "`" + `; err != nil || string(b) != want {
+		t.Errorf("encoding a key: expected %s; got %s (%v)", want, b, err)
+	}
+	if err := p.UnmarshalText([]byte("Write|Execute")); err != nil || p != Write|Execute {
+		t.Errorf("decoding text: got %d (%v)", p, err)
+	}
+	if err := p.UnmarshalText(nil); err != nil || p != None {
+		t.Errorf("decoding empty text: got %d (%v)", p, err)
+	}
+
+	for in, want := range map[Permission]string{None: "None", ReadWrite: "ReadWrite", Read | Execute: "Read|Execute", 16: "Permission(16)"} {
+		if got := in.String(); got != want {
+			t.Errorf("expected String of %d to be %s; got %s", in, want, got)
+		}
+	}
+	if !(Read | Execute).IsValid() || Permission(16).IsValid() {
+		t.Errorf("expected only combinations of flags to be valid")
+	}
+}
+`
+
+func TestGenerateFlags(t *testing.T) {
+	testGenerated(t, generator{flags: true, text: true},
+		map[string]string{"permission.go": permissionCode, "permission_test.go": permissionTest},
+		map[string][]string{"permission_jsonenums.go": {"Permission"}})
+
+	pkg, _ := parseModule(t, map[string]string{"permission.go": permissionCode, "clothes.go": strings.Replace(clothesCode, "package clothes", "package permission", 1)})
+	for _, tt := range []struct {
+		g   generator
+		typ string
+	}{
+		{generator{flags: true, intFormat: true}, "Permission"},
+		{generator{flags: true, sql: true}, "Permission"},
+		{generator{flags: true}, "Fabric"},
+	} {
+		tt.g.pkg = pkg
+		if _, err := tt.g.generate([]string{tt.typ}); err == nil {
+			t.Errorf("expected error generating %s with %+v", tt.typ, tt.g)
+		}
+	}
+}
+
+func TestLoadPatterns(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"a/a.go":     "package a\n\ntype A int\n",
+		"b/b.go":     "package b\n\ntype B int\n",
+		"b/c/c.go":   "package c\n\ntype C int\n",
+		"notadir.go": "package gen\n",
+	})
+	t.Chdir(dir)
+
+	for _, tt := range []struct {
+		args     []string
+		dir      string
+		patterns []string
+	}{
+		{nil, ".", []string{"."}},
+		{[]string{"a"}, "a", []string{"."}},
+		{[]string{"a", "b"}, ".", []string{"./a", "./b"}},
+		{[]string{"./a", "b/..."}, ".", []string{"./a", "./b/..."}},
+		{[]string{"a", "example.com/other", "notadir.go"}, ".", []string{"./a", "example.com/other", "notadir.go"}},
+	} {
+		dir, patterns := loadPatterns(tt.args)
+		if dir != tt.dir || strings.Join(patterns, " ") != strings.Join(tt.patterns, " ") {
+			t.Errorf("loadPatterns(%q): expected %s and %q; got %s and %q", tt.args, tt.dir, tt.patterns, dir, patterns)
+		}
+	}
+
+	// Plain directories are loaded as such, and not as import paths.
+	dir, patterns := loadPatterns([]string{"a", "b"})
+	pkgs, errs, err := parser.ParsePackages(dir, patterns...)
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("parse packages: %v %v", err, errs)
+	}
+	var paths []string
+	for _, pkg := range pkgs {
+		paths = append(paths, pkg.Path)
+	}
+	if got, want := strings.Join(paths, " "), "example.com/gen/a example.com/gen/b"; got != want {
+		t.Errorf("expected packages %s; got %s", want, got)
+	}
+}
+
+func TestCheckOutput(t *testing.T) {
+	pkg, dir := parseModule(t, map[string]string{"clothes.go": clothesCode})
+	g, err := recordedGenerator("-type=ShirtSize -transform=lower -sql")
+	must(t, err)
+	if g.transform == nil || !g.sql || !g.text {
+		t.Fatalf("expected the flags to be parsed from the command; got %+v", g)
+	}
+	g.pkg = pkg
+	types := []string{"ShirtSize"}
+	src, err := g.generate(types)
+	must(t, err)
+	name := filepath.Join(dir, "shirtsize_jsonenums.go")
+	must(t, ioutil.WriteFile(name, src, 0644))
+
+	// The flags in the command recorded in the file are used, whatever the
+	// check is run with.
+	g = &generator{pkg: pkg, command: "-check -all", text: true}
+	checked, err := g.generate(types)
+	must(t, err)
+	var buf bytes.Buffer
+	if err := checkOutput(&buf, g, types, name, checked); err != nil {
+		t.Errorf("expected up to date output; got %v\n%s", err, buf.String())
+	}
+
+	stale := strings.Replace(string(src), `"xl"`, `"xxl"`, -1)
+	must(t, ioutil.WriteFile(name, []byte(stale), 0644))
+	buf.Reset()
+	if err := checkOutput(&buf, g, types, name, checked); err == nil {
+		t.Errorf("expected error for stale output")
+	}
+	if diff := buf.String(); !strings.Contains(diff, "\n-\t\t\"xxl\": XL,\n") || !strings.Contains(diff, "\n+\t\t\"xl\": XL,\n") {
+		t.Errorf("expected a diff from xxl to xl; got\n%s", diff)
+	}
+
+	bad := strings.Replace(string(src), "-transform=lower", "-transform=shout", 1)
+	must(t, ioutil.WriteFile(name, []byte(bad), 0644))
+	if err := checkOutput(&buf, g, types, name, checked); err == nil || !strings.Contains(err.Error(), "invalid -transform") {
+		t.Errorf("expected error for an invalid recorded command; got %v", err)
+	}
+
+	must(t, os.Remove(name))
+	buf.Reset()
+	if err := checkOutput(&buf, g, types, name, checked); err == nil {
+		t.Errorf("expected error for missing output")
+	}
+	if diff := buf.String(); !strings.Contains(diff, "@@ -0,0 +1,") {
+		t.Errorf("expected a diff creating the file; got\n%s", diff)
+	}
+}
+
+func TestCheckOutputDir(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"clothes.go":       clothesCode,
+		"other/other.go":   "package other\n",
+		"nested/nested.go": "package clothes\n",
+	})
+	for name, ok := range map[string]bool{
+		"Clothes_JSON.go":        true,
+		"./clothes_jsonenums.go": true,
+		"nested/clothes.go":      false,
+		"nested/../clothes.go":   true,
+		"missing/sub/enums.go":   false,
+		"other/clothes.go":       false,
+		"../clothes.go":          false,
+	} {
+		err := checkOutputDir(filepath.Join(dir, name), dir)
+		if ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		} else if !ok && err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	// Relative names are compared once made absolute.
+	t.Chdir(dir)
+	must(t, checkOutputDir("clothes_jsonenums.go", dir))
+	if err := checkOutputDir("nested/clothes.go", "."); err == nil {
+		t.Errorf("expected error for a relative output in a subdirectory")
+	}
+}
+
+func TestGenerateIntFormat(t *testing.T) {
+	testGenerated(t, generator{intFormat: true, text: true, xml: true},
+		map[string]string{"level.go": levelCode, "level_test.go": levelIntTest},
+		map[string][]string{"level_jsonenums.go": {"Level", "Signed", "Named"}})
+
+	// The flags are ignored for string types, so they can be given along
+	// with -all.
+	pkg, _ := parseModule(t, map[string]string{"clothes.go": clothesCode})
+	want, err := (&generator{pkg: pkg}).generate([]string{"Fabric"})
+	must(t, err)
+	got, err := (&generator{pkg: pkg, intFormat: true, lenient: true}).generate([]string{"Fabric"})
+	must(t, err)
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected -format=int and -lenient to be ignored for string types; got\n%s", got)
+	}
+}
+
+func TestGenerateDuplicateNames(t *testing.T) {
+	pkg, _ := parseModule(t, map[string]string{"status.go": `package status
+
+type Status string
+
+const (
+	Active  Status = "active"
+	Enabled Status = "active" //jsonenums:alias=on
+	On      Status = "active" //jsonenums:alias=on
+)
+
+type Level int
+
+const (
+	Low  Level = iota //jsonenums:name=level
+	High              //jsonenums:name=level
+)
+`})
+	g := &generator{pkg: pkg}
+	src, err := g.generate([]string{"Status"})
+	must(t, err)
+	if n := strings.Count(string(src), `"active": Active,`); n != 1 {
+		t.Errorf("expected the name of Active to be listed once; got %d times in\n%s", n, src)
+	}
+	if _, err := g.generate([]string{"Level"}); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Errorf("expected error for a name used by two values; got %v", err)
+	}
+}
+
+func TestGenerateTransform(t *testing.T) {
+	pkg, _ := parseModule(t, map[string]string{
+		"status.go": `package status
+
+type Status int
+
+const (
+	StatusTodo Status = iota
+	StatusInProgress
+)
+`,
+	})
+	g := &generator{pkg: pkg, transform: transforms["snake"], trimPrefix: "Status"}
+	src, err := g.generate([]string{"Status"})
+	must(t, err)
+	for _, want := range []string{
+		`"in_progress": StatusInProgress,`,
+		`StatusInProgress: "in_progress",`,
+		`"todo":        StatusTodo,`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("expected generated code to contain %s; got\n%s", want, src)
+		}
+	}
+}
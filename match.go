@@ -0,0 +1,72 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A matchPolicy lists how names are normalized when decoding, after no exact
+// match has been found. The zero value only allows exact matches.
+type matchPolicy struct {
+	// Fold ignores case.
+	Fold bool
+	// Trim ignores leading and trailing white space.
+	Trim bool
+	// Separators considers hyphens and underscores equivalent.
+	Separators bool
+}
+
+// parseMatchPolicy parses a comma-separated list of the options in a
+// matchPolicy: fold, trim and separators.
+func parseMatchPolicy(s string) (matchPolicy, error) {
+	var m matchPolicy
+	if s == "" || s == "exact" {
+		return m, nil
+	}
+	for _, opt := range strings.Split(s, ",") {
+		switch strings.TrimSpace(opt) {
+		case "fold":
+			m.Fold = true
+		case "trim":
+			m.Trim = true
+		case "separators":
+			m.Separators = true
+		default:
+			return m, fmt.Errorf("unknown match option %q, must be fold, trim or separators", opt)
+		}
+	}
+	return m, nil
+}
+
+// Normalize reports whether any normalization is done.
+func (m matchPolicy) Normalize() bool {
+	return m.Fold || m.Trim || m.Separators
+}
+
+// normalize returns the normalized form of a name. It must be kept in sync
+// with the normalize function in the template.
+func (m matchPolicy) normalize(s string) string {
+	if m.Trim {
+		s = strings.TrimSpace(s)
+	}
+	if m.Fold {
+		s = strings.ToLower(s)
+	}
+	if m.Separators {
+		s = strings.Replace(s, "-", "_", -1)
+	}
+	return s
+}
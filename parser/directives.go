@@ -0,0 +1,127 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strings"
+)
+
+// directivePrefix starts the comments that configure jsonenums, such as
+//
+//	//jsonenums:name=acetaminophen
+const directivePrefix = "//jsonenums:"
+
+// A directive is a jsonenums comment directive, split into its key and its
+// optional value.
+type directive struct {
+	key, value string
+}
+
+// directives returns the jsonenums directives found in the given comments.
+func directives(groups ...*ast.CommentGroup) []directive {
+	var ds []directive
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, c := range g.List {
+			if !strings.HasPrefix(c.Text, directivePrefix) {
+				continue
+			}
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, directivePrefix))
+			key, value, _ := strings.Cut(text, "=")
+			ds = append(ds, directive{key: key, value: value})
+		}
+	}
+	return ds
+}
+
+// jsonTag returns the name in a line comment written as a struct tag, as in
+//
+//	Paracetamol // json:"acetaminophen"
+//
+// As in encoding/json, the options after a comma are ignored, and the tag "-"
+// or an empty name leave the constant without a name.
+func jsonTag(comment *ast.CommentGroup) (string, bool) {
+	if comment == nil {
+		return "", false
+	}
+	for _, c := range comment.List {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if tag, ok := reflect.StructTag(text).Lookup("json"); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "" || tag == "-" {
+				return "", false
+			}
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// typeDirectives reports whether the comments of a type declaration mark it
+// as an enum with a jsonenums:enum directive.
+func typeDirectives(doc, comment *ast.CommentGroup) (isEnum bool, err error) {
+	for _, d := range directives(doc, comment) {
+		switch d.key {
+		case "enum":
+			if d.value != "" {
+				return false, fmt.Errorf("directive jsonenums:enum takes no value")
+			}
+			isEnum = true
+		default:
+			return false, fmt.Errorf("unknown directive jsonenums:%s", d.key)
+		}
+	}
+	return isEnum, nil
+}
+
+// A valueConfig holds what the comments of a constant declaration configure.
+type valueConfig struct {
+	name      string
+	aliases   []string
+	isDefault bool
+}
+
+// valueDirectives returns the configuration given in the comments of a
+// constant declaration.
+func valueDirectives(doc, comment *ast.CommentGroup) (valueConfig, error) {
+	var cfg valueConfig
+	cfg.name, _ = jsonTag(comment)
+	for _, d := range directives(doc, comment) {
+		switch d.key {
+		case "name":
+			if d.value == "" {
+				return cfg, fmt.Errorf("directive jsonenums:name needs a value")
+			}
+			cfg.name = d.value
+		case "alias":
+			if d.value == "" {
+				return cfg, fmt.Errorf("directive jsonenums:alias needs a value")
+			}
+			cfg.aliases = append(cfg.aliases, d.value)
+		case "default":
+			if d.value != "" {
+				return cfg, fmt.Errorf("directive jsonenums:default takes no value")
+			}
+			cfg.isDefault = true
+		default:
+			return cfg, fmt.Errorf("unknown directive jsonenums:%s", d.key)
+		}
+	}
+	return cfg, nil
+}
@@ -12,57 +12,357 @@
 // limitations under the License.
 
 // Package parser parses Go code and keeps track of all the types defined
-// and provides access to all the constants defined for an int type.
+// and provides access to all the constants defined for an int or string type.
 package parser
 
 import (
 	"fmt"
 	"go/ast"
-	"go/build"
 	"go/constant"
 	"go/token"
 	"go/types"
 	"log"
+	"path/filepath"
 	"strings"
 
-	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/packages"
 )
 
 // A Package contains all the information related to a parsed package.
 type Package struct {
-	Name  string
+	Name string
+	// Path is the import path of the package.
+	Path string
+	// Dir is the directory holding the files of the package.
+	Dir   string
 	files []*ast.File
 
-	defs map[*ast.Ident]types.Object
+	fset  *token.FileSet
+	types *types.Package
+	defs  map[*ast.Ident]types.Object
+
+	// generated contains the names of the files generated by jsonenums.
+	generated map[string]bool
+}
+
+// An EnumValue describes one of the constants defined for a type.
+type EnumValue struct {
+	// Name is the identifier of the constant.
+	Name string
+	// Value is the value of the constant, as computed by the type checker.
+	// Its kind is either constant.Int or constant.String.
+	Value constant.Value
+	// Doc is the text of the doc comment of the constant, if any.
+	Doc string
+	// Comment is the text of the line comment of the constant, if any.
+	Comment string
+	// Position is the position of the identifier in the source code.
+	Position token.Position
+	// Exported reports whether the constant is exported.
+	Exported bool
+	// JSONName is the name given to the constant in JSON with either a
+	// json:"name" line comment or a //jsonenums:name=name directive.
+	// It is empty if no name was given.
+	JSONName string
+	// Aliases are the names given with //jsonenums:alias=name directives,
+	// accepted when decoding but never used for encoding.
+	Aliases []string
+	// Default is set for the constant marked with a //jsonenums:default
+	// directive, which decoding uses for input that isn't the name or value
+	// of any constant.
+	Default bool
 }
 
+// String returns the name of the constant, so an EnumValue prints in templates
+// the same way its name would.
+func (v EnumValue) String() string { return v.Name }
+
+// generatedHeader starts the comment at the top of the files generated by
+// jsonenums.
+const generatedHeader = "Code generated by jsonenums"
+
+// loadMode is the information needed from go/packages to find the constants
+// of a type and their values.
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+	packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps
+
 // ParsePackage parses the package in the given directory and returns it.
+//
+// The package is loaded with go/packages, so the directory is resolved the same
+// way the go command would: go.mod, go.work, vendor directories, replace
+// directives and GOFLAGS are all honoured.
 func ParsePackage(directory string) (*Package, error) {
-	p, err := build.ImportDir(directory, build.FindOnly)
+	pkgs, errs, err := ParsePackages(directory, ".")
 	if err != nil {
-		return nil, fmt.Errorf("provided directory (%s) may not under GOPATH (%s): %v",
-			directory, build.Default.GOPATH, err)
+		return nil, err
 	}
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", directory, len(pkgs))
+	}
+	return pkgs[0], nil
+}
 
-	conf := loader.Config{TypeChecker: types.Config{FakeImportC: true}}
-	conf.Import(p.ImportPath)
-	program, err := conf.Load()
+// ParsePackages loads the packages matching the given patterns, such as
+// "./...", as the go command would from the given directory. All of them are
+// loaded with a single call, and those that fail to load are reported in errs,
+// one error per package, rather than making the whole call fail.
+func ParsePackages(directory string, patterns ...string) (pkgs []*Package, errs []error, err error) {
+	conf := &packages.Config{Mode: loadMode, Dir: directory}
+	loaded, err := packages.Load(conf, patterns...)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't load package: %v", err)
+		return nil, nil, fmt.Errorf("couldn't load packages in %s: %v", directory, err)
+	}
+	for _, pkg := range loaded {
+		p, err := newPackage(pkg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, errs, nil
+}
+
+// newPackage builds a Package from one loaded by go/packages.
+func newPackage(pkg *packages.Package) (*Package, error) {
+	if len(pkg.Errors) > 0 {
+		var errs []string
+		for _, err := range pkg.Errors {
+			errs = append(errs, err.Error())
+		}
+		return nil, fmt.Errorf("couldn't load package %s:\n\t%v", pkg.PkgPath, strings.Join(errs, "\n\t"))
+	}
+	if len(pkg.GoFiles) == 0 {
+		return nil, fmt.Errorf("couldn't load package %s: no Go files", pkg.PkgPath)
+	}
+
+	generated := make(map[string]bool)
+	for _, file := range pkg.Syntax {
+		if len(file.Comments) > 0 && strings.HasPrefix(file.Comments[0].Text(), generatedHeader) {
+			generated[pkg.Fset.Position(file.Pos()).Filename] = true
+		}
 	}
 
-	pkgInfo := program.Package(p.ImportPath)
 	return &Package{
-		Name:  pkgInfo.Pkg.Name(),
-		files: pkgInfo.Files,
-		defs:  pkgInfo.Defs,
+		Name:      pkg.Name,
+		Path:      pkg.PkgPath,
+		Dir:       filepath.Dir(pkg.GoFiles[0]),
+		files:     pkg.Syntax,
+		fset:      pkg.Fset,
+		types:     pkg.Types,
+		defs:      pkg.TypesInfo.Defs,
+		generated: generated,
 	}, nil
 }
 
-// generate produces the String method for the named type.
+// HasMethod reports whether the named type has a method with the given name,
+// declared either with a value or a pointer receiver.
+// Methods declared in files generated by jsonenums are ignored.
+func (pkg *Package) HasMethod(typeName, method string) (bool, error) {
+	typ, err := pkg.lookupType(typeName)
+	if err != nil {
+		return false, err
+	}
+	return pkg.method(types.NewPointer(typ), method) != nil, nil
+}
+
+// IsStringer reports whether the named type implements fmt.Stringer.
+// Methods declared in files generated by jsonenums are ignored.
+func (pkg *Package) IsStringer(typeName string) (bool, error) {
+	typ, err := pkg.lookupType(typeName)
+	if err != nil {
+		return false, err
+	}
+	fn := pkg.method(typ, "String")
+	if fn == nil {
+		return false, nil
+	}
+	sig := fn.Type().(*types.Signature)
+	if _, ptr := sig.Recv().Type().(*types.Pointer); ptr {
+		return false, nil
+	}
+	return types.Identical(sig, stringSignature), nil
+}
+
+// Underlying returns the name of the basic type underlying the named type,
+// such as "uint8" or "string".
+func (pkg *Package) Underlying(typeName string) (string, error) {
+	typ, err := pkg.lookupType(typeName)
+	if err != nil {
+		return "", err
+	}
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return "", fmt.Errorf("type %s is not a basic type", typeName)
+	}
+	return basic.Name(), nil
+}
+
+// stringSignature is the signature of the String method of fmt.Stringer.
+var stringSignature = types.NewSignatureType(nil, nil, nil, nil,
+	types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.String])), false)
+
+// method returns the method with the given name of typ, or nil if there is
+// none or it's declared in a file generated by jsonenums.
+func (pkg *Package) method(typ types.Type, name string) *types.Func {
+	obj, _, _ := types.LookupFieldOrMethod(typ, true, pkg.types, name)
+	fn, ok := obj.(*types.Func)
+	if !ok || pkg.generated[pkg.fset.Position(fn.Pos()).Filename] {
+		return nil
+	}
+	return fn
+}
+
+// isGenerated reports whether the file was generated by jsonenums.
+func (pkg *Package) isGenerated(file *ast.File) bool {
+	return pkg.generated[pkg.fset.Position(file.Pos()).Filename]
+}
+
+// EnumTypes returns the names of all the types declared in the package with
+// an integer or string underlying type and typed constants, in the order
+// they are declared.
+func (pkg *Package) EnumTypes() ([]string, error) {
+	declared, err := pkg.enumTypes()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range declared {
+		if t.enum {
+			names = append(names, t.name)
+		}
+	}
+	return names, nil
+}
+
+// MarkedEnumTypes returns the names of the types declared in the package with
+// a //jsonenums:enum directive, in the order they are declared. It fails if
+// any of them has no typed constants or isn't an integer or string type.
+func (pkg *Package) MarkedEnumTypes() ([]string, error) {
+	declared, err := pkg.enumTypes()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range declared {
+		if !t.marked {
+			continue
+		}
+		if !t.enum {
+			return nil, fmt.Errorf("%v: type %s is marked with jsonenums:enum, "+
+				"but it isn't an integer or string type with typed constants", t.pos, t.name)
+		}
+		names = append(names, t.name)
+	}
+	return names, nil
+}
+
+// A declaredType is a type declared in the package, as found by enumTypes.
+type declaredType struct {
+	name string
+	pos  token.Position
+	// enum reports whether it's an integer or string type with typed
+	// constants, and marked whether it has a jsonenums:enum directive.
+	enum, marked bool
+}
+
+// enumTypes returns all the types declared in the package outside of
+// generated files, in declaration order.
+func (pkg *Package) enumTypes() ([]declaredType, error) {
+	var files []*ast.File
+	for _, file := range pkg.files {
+		if !pkg.isGenerated(file) {
+			files = append(files, file)
+		}
+	}
+
+	// Find the types given to constants first.
+	hasConsts := make(map[string]bool)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.CONST {
+				continue
+			}
+			typ := ""
+			for _, spec := range decl.Specs {
+				typ = specType(spec.(*ast.ValueSpec), typ)
+				hasConsts[typ] = true
+			}
+		}
+	}
+
+	var declared []declaredType
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				tspec := spec.(*ast.TypeSpec) // Guaranteed to succeed as this is TYPE.
+				// A type declaration without parentheses has its doc comment
+				// attached to the declaration rather than to the spec.
+				doc := tspec.Doc
+				if doc == nil && !decl.Lparen.IsValid() {
+					doc = decl.Doc
+				}
+				marked, err := typeDirectives(doc, tspec.Comment)
+				if err != nil {
+					return nil, fmt.Errorf("%v: %v", pkg.fset.Position(tspec.Pos()), err)
+				}
+				t := declaredType{
+					name:   tspec.Name.Name,
+					pos:    pkg.fset.Position(tspec.Pos()),
+					marked: marked,
+				}
+				if obj, ok := pkg.defs[tspec.Name].(*types.TypeName); ok && !obj.IsAlias() && hasConsts[t.name] {
+					basic, ok := obj.Type().Underlying().(*types.Basic)
+					t.enum = ok && basic.Info()&(types.IsInteger|types.IsString) != 0
+				}
+				declared = append(declared, t)
+			}
+		}
+	}
+	return declared, nil
+}
+
+// lookupType returns the type with the given name declared in the package.
+func (pkg *Package) lookupType(typeName string) (types.Type, error) {
+	obj, ok := pkg.types.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type %s defined in package %s", typeName, pkg.Name)
+	}
+	return obj.Type(), nil
+}
+
+// ValuesOfType returns the names of the constants defined for the named type,
+// in declaration order.
 func (pkg *Package) ValuesOfType(typeName string) ([]string, error) {
-	var values, inspectErrs []string
+	values, err := pkg.ValuesOfTypeDetailed(typeName)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = v.Name
+	}
+	return names, nil
+}
+
+// ValuesOfTypeDetailed returns all the information known about the constants
+// defined for the named type, in declaration order.
+func (pkg *Package) ValuesOfTypeDetailed(typeName string) ([]EnumValue, error) {
+	var values []EnumValue
+	var inspectErrs []string
 	for _, file := range pkg.files {
+		if pkg.isGenerated(file) {
+			// Generated files declare constants for their own use.
+			continue
+		}
 		ast.Inspect(file, func(node ast.Node) bool {
 			decl, ok := node.(*ast.GenDecl)
 			if !ok || decl.Tok != token.CONST {
@@ -84,40 +384,71 @@ func (pkg *Package) ValuesOfType(typeName string) ([]string, error) {
 	if len(values) == 0 {
 		return nil, fmt.Errorf("no values defined for type %s", typeName)
 	}
+	var def *EnumValue
+	for i, v := range values {
+		if !v.Default {
+			continue
+		}
+		if def != nil {
+			return nil, fmt.Errorf("%v: %s is marked as default, but %s already is",
+				v.Position, v.Name, def.Name)
+		}
+		def = &values[i]
+	}
 	return values, nil
 }
 
-func (pkg *Package) valuesOfTypeIn(typeName string, decl *ast.GenDecl) ([]string, error) {
-	var values []string
+// specType returns the name of the type of the constants declared by vspec,
+// given the one of the previous spec in the same declaration. It's empty if
+// the constants are untyped or their type is declared in another package.
+func specType(vspec *ast.ValueSpec, prev string) string {
+	if vspec.Type == nil && len(vspec.Values) > 0 {
+		// "X = 1". With no type but a value, the constant is untyped.
+		return ""
+	}
+	if vspec.Type != nil {
+		// "X T". We have a type.
+		if ident, ok := vspec.Type.(*ast.Ident); ok {
+			return ident.Name
+		}
+		return ""
+	}
+	// If the type and value are both missing, we carry down the type (and
+	// value, but the "go/types" package takes care of that).
+	return prev
+}
+
+func (pkg *Package) valuesOfTypeIn(typeName string, decl *ast.GenDecl) ([]EnumValue, error) {
+	var values []EnumValue
 
 	// The name of the type of the constants we are declaring.
 	// Can change if this is a multi-element declaration.
 	typ := ""
 	// Loop over the elements of the declaration. Each element is a ValueSpec:
 	// a list of names possibly followed by a type, possibly followed by values.
-	// If the type and value are both missing, we carry down the type (and value,
-	// but the "go/types" package takes care of that).
 	for _, spec := range decl.Specs {
 		vspec := spec.(*ast.ValueSpec) // Guaranteed to succeed as this is CONST.
-		if vspec.Type == nil && len(vspec.Values) > 0 {
-			// "X = 1". With no type but a value, the constant is untyped.
-			// Skip this vspec and reset the remembered type.
-			typ = ""
-			continue
-		}
-		if vspec.Type != nil {
-			// "X T". We have a type. Remember it.
-			ident, ok := vspec.Type.(*ast.Ident)
-			if !ok {
-				continue
-			}
-			typ = ident.Name
-		}
+		typ = specType(vspec, typ)
 		if typ != typeName {
 			// This is not the type we're looking for.
 			continue
 		}
 
+		// A const declaration without parentheses has its doc comment
+		// attached to the declaration rather than to the spec.
+		doc := vspec.Doc
+		if doc == nil && !decl.Lparen.IsValid() {
+			doc = decl.Doc
+		}
+		cfg, err := valueDirectives(doc, vspec.Comment)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", pkg.fset.Position(vspec.Pos()), err)
+		}
+		if (cfg.name != "" || len(cfg.aliases) > 0 || cfg.isDefault) && len(vspec.Names) > 1 {
+			return nil, fmt.Errorf("%v: directives can only be given to a single constant",
+				pkg.fset.Position(vspec.Pos()))
+		}
+
 		// We now have a list of names (from one line of source code) all being
 		// declared with the desired type.
 		// Grab their names and actual values and store them in f.values.
@@ -133,14 +464,24 @@ func (pkg *Package) valuesOfTypeIn(typeName string, decl *ast.GenDecl) ([]string
 				return nil, fmt.Errorf("no value for constant %s", name)
 			}
 			info := obj.Type().Underlying().(*types.Basic).Info()
-			if info&types.IsInteger == 0 {
-				return nil, fmt.Errorf("can't handle non-integer constant type %s", typ)
+			if info&(types.IsInteger|types.IsString) == 0 {
+				return nil, fmt.Errorf("can't handle constant type %s: must be an integer or a string", typ)
 			}
 			value := obj.(*types.Const).Val() // Guaranteed to succeed as this is CONST.
-			if value.Kind() != constant.Int {
-				log.Fatalf("can't happen: constant is not an integer %s", name)
+			if k := value.Kind(); k != constant.Int && k != constant.String {
+				log.Fatalf("can't happen: constant is not an integer or a string %s", name)
 			}
-			values = append(values, name.Name)
+			values = append(values, EnumValue{
+				Name:     name.Name,
+				Value:    value,
+				Doc:      doc.Text(),
+				Comment:  vspec.Comment.Text(),
+				Position: pkg.fset.Position(name.Pos()),
+				Exported: name.IsExported(),
+				JSONName: cfg.name,
+				Aliases:  cfg.aliases,
+				Default:  cfg.isDefault,
+			})
 		}
 	}
 	return values, nil
@@ -18,6 +18,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -46,7 +47,533 @@ func TestParseFromMultipleGopath(t *testing.T) {
 	must(t, os.MkdirAll(dir, 0755))
 	must(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(fakeCode), 0644))
 
+	t.Setenv("GO111MODULE", "off")
 	if _, err := ParsePackage(dir); err != nil {
 		t.Fatalf("Parse package (%v): %v", dir, err)
 	}
 }
+
+// writeTree creates the given files, keyed by slash-separated paths, under a
+// new temporary directory and returns its path.
+func writeTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		must(t, os.MkdirAll(filepath.Dir(path), 0755))
+		must(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	return root
+}
+
+// writeModule creates a new module with the given files, as writeTree does,
+// adding a go.mod file unless one is given, and makes sure the environment
+// doesn't force a different module mode or workspace on it.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "")
+	if _, ok := files["go.mod"]; !ok {
+		files["go.mod"] = "module example.com/test\n\ngo 1.18\n"
+	}
+	return writeTree(t, files)
+}
+
+// parseTree creates a new module with the given files, see writeModule, and
+// parses the package at its root.
+func parseTree(t *testing.T, files map[string]string) *Package {
+	t.Helper()
+	pkg, err := ParsePackage(writeModule(t, files))
+	if err != nil {
+		t.Fatalf("parse package: %v", err)
+	}
+	return pkg
+}
+
+const sizeCode = `
+package shirt
+
+import "example.com/dep"
+
+type Size int
+
+const (
+	Small Size = iota
+	Medium
+	Large Size = dep.Large
+)
+`
+
+const depCode = `
+package dep
+
+const Large = 2
+`
+
+func TestParseLayouts(t *testing.T) {
+	tests := []struct {
+		name  string
+		dir   string
+		files map[string]string
+	}{
+		{
+			name: "module with replace",
+			dir:  "shirt",
+			files: map[string]string{
+				"shirt/go.mod":   "module example.com/shirt\n\ngo 1.18\n\nrequire example.com/dep v1.0.0\n\nreplace example.com/dep => ../dep\n",
+				"shirt/shirt.go": sizeCode,
+				"dep/go.mod":     "module example.com/dep\n\ngo 1.18\n",
+				"dep/dep.go":     depCode,
+			},
+		},
+		{
+			name: "vendored module",
+			dir:  "shirt",
+			files: map[string]string{
+				"shirt/go.mod":                        "module example.com/shirt\n\ngo 1.18\n\nrequire example.com/dep v1.0.0\n",
+				"shirt/shirt.go":                      sizeCode,
+				"shirt/vendor/modules.txt":            "# example.com/dep v1.0.0\n## explicit\nexample.com/dep\n",
+				"shirt/vendor/example.com/dep/dep.go": depCode,
+			},
+		},
+		{
+			name: "workspace",
+			dir:  "shirt",
+			files: map[string]string{
+				"go.work":        "go 1.18\n\nuse (\n\t./shirt\n\t./dep\n)\n",
+				"shirt/go.mod":   "module example.com/shirt\n\ngo 1.18\n",
+				"shirt/shirt.go": sizeCode,
+				"dep/go.mod":     "module example.com/dep\n\ngo 1.18\n",
+				"dep/dep.go":     depCode,
+			},
+		},
+	}
+
+	// The layouts are self-contained; make sure the environment doesn't
+	// force a different module mode or workspace on them.
+	t.Setenv("GO111MODULE", "on")
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "")
+	t.Setenv("GOPROXY", "off")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			pkg, err := ParsePackage(filepath.Join(root, tt.dir))
+			if err != nil {
+				t.Fatalf("parse package: %v", err)
+			}
+			if pkg.Name != "shirt" {
+				t.Errorf("expected package name shirt; got %s", pkg.Name)
+			}
+			values, err := pkg.ValuesOfType("Size")
+			if err != nil {
+				t.Fatalf("values of type Size: %v", err)
+			}
+			if got, want := strings.Join(values, ","), "Small,Medium,Large"; got != want {
+				t.Errorf("expected values %s; got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"main.go": "package broken\n\nconst X = undefined\n",
+	})
+	if _, err := ParsePackage(root); err == nil {
+		t.Fatal("expected error parsing package with type errors")
+	}
+}
+
+func TestParsePackages(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"mono.go":            "package mono\n",
+		"pill/pill.go":       "package pill\n\ntype Pill int\n",
+		"broken/broken.go":   "package broken\n\nconst X = undefined\n",
+		"shirt/size/size.go": "package size\n\ntype Size string\n",
+	})
+	pkgs, errs, err := ParsePackages(root, "./...")
+	if err != nil {
+		t.Fatalf("parse packages: %v", err)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "example.com/test/broken") {
+		t.Errorf("expected a single error for package broken; got %v", errs)
+	}
+	dirs := map[string]string{
+		"example.com/test":            root,
+		"example.com/test/pill":       filepath.Join(root, "pill"),
+		"example.com/test/shirt/size": filepath.Join(root, "shirt", "size"),
+	}
+	if len(pkgs) != len(dirs) {
+		t.Errorf("expected %d packages; got %d", len(dirs), len(pkgs))
+	}
+	for _, pkg := range pkgs {
+		if want, ok := dirs[pkg.Path]; !ok {
+			t.Errorf("unexpected package %s", pkg.Path)
+		} else if pkg.Dir != want {
+			t.Errorf("expected directory %s for %s; got %s", want, pkg.Path, pkg.Dir)
+		}
+	}
+}
+
+const detailedCode = `
+package pill
+
+type Pill int
+
+const (
+	// Placebo does nothing.
+	Placebo Pill = iota
+	Aspirin   // for headaches
+	Ibuprofen
+	paracetamol
+	Acetaminophen = paracetamol
+)
+
+// Vitamin is good for you.
+const Vitamin Pill = 10
+`
+
+func TestValuesOfTypeDetailed(t *testing.T) {
+	pkg := parseTree(t, map[string]string{
+		"pill.go": detailedCode,
+	})
+	values, err := pkg.ValuesOfTypeDetailed("Pill")
+	if err != nil {
+		t.Fatalf("values of type Pill: %v", err)
+	}
+
+	want := []struct {
+		name     string
+		value    string
+		doc      string
+		comment  string
+		line     int
+		exported bool
+	}{
+		{"Placebo", "0", "Placebo does nothing.\n", "", 8, true},
+		{"Aspirin", "1", "", "for headaches\n", 9, true},
+		{"Ibuprofen", "2", "", "", 10, true},
+		{"paracetamol", "3", "", "", 11, false},
+		{"Vitamin", "10", "Vitamin is good for you.\n", "", 16, true},
+	}
+	if len(values) != len(want) {
+		t.Fatalf("expected %d values; got %v", len(want), values)
+	}
+	for i, w := range want {
+		v := values[i]
+		if v.Name != w.name {
+			t.Errorf("value %d: expected name %s; got %s", i, w.name, v.Name)
+		}
+		if v.Value.String() != w.value {
+			t.Errorf("%s: expected value %s; got %s", w.name, w.value, v.Value)
+		}
+		if v.Doc != w.doc {
+			t.Errorf("%s: expected doc %q; got %q", w.name, w.doc, v.Doc)
+		}
+		if v.Comment != w.comment {
+			t.Errorf("%s: expected comment %q; got %q", w.name, w.comment, v.Comment)
+		}
+		if v.Position.Line != w.line || filepath.Base(v.Position.Filename) != "pill.go" {
+			t.Errorf("%s: expected position pill.go:%d; got %v", w.name, w.line, v.Position)
+		}
+		if v.Exported != w.exported {
+			t.Errorf("%s: expected exported %v; got %v", w.name, w.exported, v.Exported)
+		}
+	}
+}
+
+func TestValuesOfStringType(t *testing.T) {
+	pkg := parseTree(t, map[string]string{
+		"status.go": `package status
+
+type Status string
+
+const (
+	Active   Status = "active"
+	Disabled Status = "disabled"
+)
+
+type Ratio float64
+
+const Half Ratio = 0.5
+`,
+	})
+	values, err := pkg.ValuesOfTypeDetailed("Status")
+	if err != nil {
+		t.Fatalf("values of type Status: %v", err)
+	}
+	if len(values) != 2 || values[1].Name != "Disabled" || values[1].Value.ExactString() != `"disabled"` {
+		t.Errorf("unexpected values %v", values)
+	}
+	if _, err := pkg.ValuesOfTypeDetailed("Ratio"); err == nil {
+		t.Errorf("expected error for float type")
+	}
+}
+
+func TestNameDirectives(t *testing.T) {
+	pkg := parseTree(t, map[string]string{
+		"pill.go": `package pill
+
+type Pill int
+
+const (
+	Placebo     Pill = iota
+	Paracetamol      // json:"acetaminophen"
+	// Ibuprofen is also known as advil.
+	//jsonenums:alias=advil
+	//jsonenums:alias=motrin
+	Ibuprofen
+	Aspirin //jsonenums:name=asa
+	//jsonenums:default
+	Other
+)
+
+type Broken int
+
+const (
+	A, B Broken = 1, 2 //jsonenums:name=ab
+)
+
+type Unknown int
+
+const C Unknown = 1 //jsonenums:nmae=c
+
+type TwoDefaults int
+
+const (
+	D TwoDefaults = iota //jsonenums:default
+	E                    //jsonenums:default
+)
+
+type DefaultValue int
+
+const F DefaultValue = 1 //jsonenums:default=F
+
+type Tagged int
+
+const (
+	G Tagged = iota // json:"g,omitempty"
+	H               // json:"-"
+	I               // json:",string"
+	J               // json:"-,"
+)
+`,
+	})
+	values, err := pkg.ValuesOfTypeDetailed("Pill")
+	if err != nil {
+		t.Fatalf("values of type Pill: %v", err)
+	}
+	if got := values[0].JSONName; got != "" {
+		t.Errorf("expected no JSON name for Placebo; got %q", got)
+	}
+	if got := values[1].JSONName; got != "acetaminophen" {
+		t.Errorf("expected JSON name acetaminophen for Paracetamol; got %q", got)
+	}
+	if got := strings.Join(values[2].Aliases, ","); got != "advil,motrin" {
+		t.Errorf("expected aliases advil,motrin for Ibuprofen; got %q", got)
+	}
+	if got := values[2].Doc; got != "Ibuprofen is also known as advil.\n" {
+		t.Errorf("expected directives to be left out of the doc; got %q", got)
+	}
+	if got := values[3].JSONName; got != "asa" {
+		t.Errorf("expected JSON name asa for Aspirin; got %q", got)
+	}
+	for _, v := range values {
+		if want := v.Name == "Other"; v.Default != want {
+			t.Errorf("expected Default of %s to be %v; got %v", v.Name, want, v.Default)
+		}
+	}
+
+	values, err = pkg.ValuesOfTypeDetailed("Tagged")
+	if err != nil {
+		t.Fatalf("values of type Tagged: %v", err)
+	}
+	for i, want := range []string{"g", "", "", "-"} {
+		if got := values[i].JSONName; got != want {
+			t.Errorf("expected JSON name %q for %s; got %q", want, values[i].Name, got)
+		}
+	}
+
+	for _, typ := range []string{"Broken", "Unknown", "TwoDefaults", "DefaultValue"} {
+		if _, err := pkg.ValuesOfTypeDetailed(typ); err == nil {
+			t.Errorf("expected error for type %s", typ)
+		}
+	}
+}
+
+func TestHasMethod(t *testing.T) {
+	pkg := parseTree(t, map[string]string{
+		"methods.go": `package methods
+
+type Value int
+
+func (Value) String() string { return "" }
+
+type Pointer int
+
+func (*Pointer) String() string { return "" }
+
+type None int
+
+type Generated int
+`,
+		"generated_jsonenums.go": `// Code generated by jsonenums -type=Generated; DO NOT EDIT.
+
+package methods
+
+func (Generated) String() string { return "" }
+`,
+	})
+	for typ, want := range map[string]bool{"Value": true, "Pointer": true, "None": false, "Generated": false} {
+		got, err := pkg.HasMethod(typ, "String")
+		if err != nil {
+			t.Fatalf("has method %s.String: %v", typ, err)
+		}
+		if got != want {
+			t.Errorf("expected HasMethod(%s, String) to be %v; got %v", typ, want, got)
+		}
+	}
+	if _, err := pkg.HasMethod("Missing", "String"); err == nil {
+		t.Errorf("expected error for missing type")
+	}
+}
+
+func TestIsStringer(t *testing.T) {
+	pkg := parseTree(t, map[string]string{
+		"methods.go": `package methods
+
+type Value int
+
+func (Value) String() string { return "" }
+
+type Pointer int
+
+func (*Pointer) String() string { return "" }
+
+type Other int
+
+func (Other) String() []byte { return nil }
+`,
+	})
+	for typ, want := range map[string]bool{"Value": true, "Pointer": false, "Other": false} {
+		got, err := pkg.IsStringer(typ)
+		if err != nil {
+			t.Fatalf("is stringer %s: %v", typ, err)
+		}
+		if got != want {
+			t.Errorf("expected IsStringer(%s) to be %v; got %v", typ, want, got)
+		}
+	}
+}
+
+func TestUnderlying(t *testing.T) {
+	pkg := parseTree(t, map[string]string{
+		"underlying.go": `package underlying
+
+type Int int
+
+type Unsigned uint16
+
+type Name string
+
+type Nested Unsigned
+
+type Struct struct{}
+`,
+	})
+	for typ, want := range map[string]string{"Int": "int", "Unsigned": "uint16", "Name": "string", "Nested": "uint16"} {
+		got, err := pkg.Underlying(typ)
+		if err != nil {
+			t.Fatalf("underlying %s: %v", typ, err)
+		}
+		if got != want {
+			t.Errorf("expected Underlying(%s) to be %s; got %s", typ, want, got)
+		}
+	}
+	if _, err := pkg.Underlying("Struct"); err == nil {
+		t.Errorf("expected error for a struct type")
+	}
+}
+
+func TestEnumTypes(t *testing.T) {
+	pkg := parseTree(t, map[string]string{
+		"enums.go": `package enums
+
+//jsonenums:enum
+type Pill int
+
+const (
+	Placebo Pill = iota
+	Aspirin
+)
+
+type (
+	Status string
+
+	//jsonenums:enum
+	Shirt uint8
+
+	Ratio float64
+
+	Untyped int
+
+	Unused int
+)
+
+const (
+	Active Status = "active"
+	XL     Shirt  = 1
+	Half   Ratio  = 0.5
+	One           = 1
+	Two    = Untyped(2)
+)
+
+type Alias = Pill
+
+type Permission int
+
+const Read Permission = 1
+`,
+		"generated_jsonenums.go": `// Code generated by jsonenums -type=Permission,Unused; DO NOT EDIT.
+
+package enums
+
+const _PermissionMask Permission = 1
+
+const _UnusedMask Unused = 0
+`,
+	})
+	all, err := pkg.EnumTypes()
+	if err != nil {
+		t.Fatalf("enum types: %v", err)
+	}
+	if got, want := strings.Join(all, ","), "Pill,Status,Shirt,Permission"; got != want {
+		t.Errorf("expected enum types %s; got %s", want, got)
+	}
+	marked, err := pkg.MarkedEnumTypes()
+	if err != nil {
+		t.Fatalf("marked enum types: %v", err)
+	}
+	if got, want := strings.Join(marked, ","), "Pill,Shirt"; got != want {
+		t.Errorf("expected marked enum types %s; got %s", want, got)
+	}
+	values, err := pkg.ValuesOfType("Permission")
+	if err != nil {
+		t.Fatalf("values of type Permission: %v", err)
+	}
+	if got := strings.Join(values, ","); got != "Read" {
+		t.Errorf("expected constants in generated files to be ignored; got %s", got)
+	}
+
+	for name, code := range map[string]string{
+		"float":     "//jsonenums:enum\ntype Ratio float64\n\nconst Half Ratio = 0.5\n",
+		"no consts": "//jsonenums:enum\ntype Pill int\n",
+		"value":     "//jsonenums:enum=yes\ntype Pill int\n\nconst Placebo Pill = 0\n",
+		"unknown":   "//jsonenums:enmu\ntype Pill int\n\nconst Placebo Pill = 0\n",
+	} {
+		pkg := parseTree(t, map[string]string{"enums.go": "package enums\n\n" + code})
+		if _, err := pkg.MarkedEnumTypes(); err == nil {
+			t.Errorf("%s: expected error for marked types", name)
+		}
+	}
+}
@@ -61,7 +61,7 @@ func generateHandler(w http.ResponseWriter, r *http.Request) error {
 		return fmt.Errorf("parse package: %v", err)
 	}
 
-	values, err := pkg.ValuesOfType(typ)
+	values, err := pkg.ValuesOfTypeDetailed(typ)
 	if err != nil {
 		return fmt.Errorf("find values: %v", err)
 	}
@@ -73,7 +73,7 @@ func generateHandler(w http.ResponseWriter, r *http.Request) error {
 	var data = struct {
 		PackageName string
 		TypeName    string
-		Values      []string
+		Values      []parser.EnumValue
 	}{pkg.Name, typ, values}
 
 	var buf bytes.Buffer
@@ -93,6 +93,12 @@ func createDir(content string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("create tmp dir: %v", err)
 	}
+	// The code is loaded as a module so it doesn't need to live in a GOPATH.
+	mod := []byte("module jsonenums\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), mod, 0644); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("create go.mod: %v", err)
+	}
 	f, err := os.Create(filepath.Join(dir, "main.go"))
 	if err != nil {
 		os.RemoveAll(dir)
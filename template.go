@@ -23,59 +23,619 @@ var generatedTmpl = template.Must(template.New("generated").Parse(`
 package {{.PackageName}}
 
 import (
+    "bytes"
+    "database/sql/driver"
     "encoding/json"
+    "encoding/xml"
     "fmt"
+    "strconv"
+    "strings"
+
+    yaml "gopkg.in/yaml.v3"
 )
 
-{{range $typename, $values := .TypesAndValues}}
+{{range .Types}}
+{{$typename := .Name}}{{$values := .Values}}
 
 var (
     _{{$typename}}NameToValue = map[string]{{$typename}} {
-        {{range $values}}"{{.}}": {{.}},
-        {{end}}
+        {{range $values}}{{if not .Duplicate}}{{template "name" .}}: {{.Name}},
+        {{end}}{{$name := .Name}}{{range .Aliases}}{{printf "%q" .}}: {{$name}},
+        {{end}}{{end}}
     }
 
     _{{$typename}}ValueToName = map[{{$typename}}]string {
-        {{range $values}}{{.}}: "{{.}}",
-        {{end}}
+        {{range $values}}{{if .Emit}}{{.Name}}: {{template "name" .}},
+        {{end}}{{end}}
+    }
+
+    _{{$typename}}Names = []string{
+        {{range $values}}{{if .Emit}}{{template "name" .}},
+        {{end}}{{end}}
     }
 )
 
-func init() {
+// Invalid{{$typename}}Error is the error returned when encoding or decoding a
+// value of {{$typename}} that isn't the one of any constant.
+type Invalid{{$typename}}Error struct {
+    // Type is the name of the type, {{$typename}}.
+    Type string
+    // Input is the offending input: the JSON or text being decoded, or the
+    // value being encoded, quoted if it's a string.
+    Input string
+    // Valid lists the names of the constants of {{$typename}}, in the order
+    // they are declared.
+    Valid []string
+
+    reason string
+}
+
+// Error describes the error along with the offending input.
+func (e *Invalid{{$typename}}Error) Error() string {
+    if e.reason == "" {
+        return fmt.Sprintf("invalid %s %s", e.Type, e.Input)
+    }
+    return fmt.Sprintf("invalid %s %s: %s", e.Type, e.Input, e.reason)
+}
+
+// _{{$typename}}Error returns an Invalid{{$typename}}Error for the given input.
+func _{{$typename}}Error(input, reason string) error {
+    return &Invalid{{$typename}}Error{
+        Type:   "{{$typename}}",
+        Input:  input,
+        Valid:  append([]string(nil), _{{$typename}}Names...),
+        reason: reason,
+    }
+}
+
+{{if $.Match.Normalize}}
+// _{{$typename}}NormalizedNameToValue is _{{$typename}}NameToValue with
+// normalized keys, used when a name doesn't match exactly.
+var _{{$typename}}NormalizedNameToValue = func() map[string]{{$typename}} {
+    m := make(map[string]{{$typename}}, len(_{{$typename}}NameToValue))
+    {{if .IsStringer}}// The names returned by String can only be checked here. Those that are
+    // the same once normalized but belong to different values are left out,
+    // so they only match exactly.
+    ambiguous := make(map[string]bool)
+    {{end}}for s, v := range _{{$typename}}NameToValue {
+        n := _{{$typename}}Normalize(s)
+        {{if .IsStringer}}if w, ok := m[n]; ok && w != v {
+            ambiguous[n] = true
+        }
+        {{end}}m[n] = v
+    }
+    {{if .IsStringer}}for n := range ambiguous {
+        delete(m, n)
+    }
+    {{end}}return m
+}()
+
+// _{{$typename}}Normalize returns the normalized form of a name of {{$typename}}.
+func _{{$typename}}Normalize(s string) string {
+    {{if $.Match.Trim}}s = strings.TrimSpace(s)
+    {{end}}{{if $.Match.Fold}}s = strings.ToLower(s)
+    {{end}}{{if $.Match.Separators}}s = strings.Replace(s, "-", "_", -1)
+    {{end}}return s
+}
+{{end}}
+
+{{if .AcceptNumbers}}
+// _{{$typename}}NumberToValue maps the JSON encoding of the values of
+// {{$typename}} back to them.
+var _{{$typename}}NumberToValue = map[string]{{$typename}} {
+    {{range $values}}{{if .Emit}}{{printf "%q" .Number}}: {{.Name}},
+    {{end}}{{end}}
+}
+{{end}}
+
+{{if $.Flags}}
+// _{{$typename}}Flags are the values of the constants of {{$typename}} with a
+// single bit set, in the order they are declared.
+var _{{$typename}}Flags = []{{$typename}}{
+    {{range $values}}{{if .Bit}}{{.Name}},
+    {{end}}{{end}}
+}
+
+// _{{$typename}}Mask has all the bits of the constants of {{$typename}} set.
+const _{{$typename}}Mask {{$typename}} = {{.Mask}}
+
+// _{{$typename}}FlagNames returns the names of the flags set in r, in the
+// order they are declared.
+func _{{$typename}}FlagNames(r {{$typename}}) ([]string, error) {
+    if r&^_{{$typename}}Mask != 0 {
+        return nil, _{{$typename}}Error(fmt.Sprintf("%d", r), fmt.Sprintf("unknown bits %#x", {{.Underlying}}(r&^_{{$typename}}Mask)))
+    }
+    names := []string{}
+    for _, f := range _{{$typename}}Flags {
+        if r&f != 0 {
+            names = append(names, _{{$typename}}ValueToName[f])
+        }
+    }
+    return names, nil
+}
+
+// _{{$typename}}ParseFlags returns the value with the flags of the given
+// names set.
+func _{{$typename}}ParseFlags(names []string) ({{$typename}}, error) {
     var v {{$typename}}
-    if _, ok := interface{}(v).(fmt.Stringer); ok {
-        _{{$typename}}NameToValue = map[string]{{$typename}} {
-            {{range $values}}interface{}({{.}}).(fmt.Stringer).String(): {{.}},
-            {{end}}
+    for _, name := range names {
+        f, err := Parse{{$typename}}(name)
+        if err != nil {
+            return 0, err
         }
+        v |= f
+    }
+    return v, nil
+}
+{{else}}
+// _{{$typename}}ValueToJSON contains the JSON encoding of the values of
+// {{$typename}}, so they don't need to be encoded every time.
+{{if and .IsStringer (not .IntFormat)}}var _{{$typename}}ValueToJSON = func() map[{{$typename}}][]byte {
+    m := make(map[{{$typename}}][]byte, len(_{{$typename}}ValueToName))
+    for v, s := range _{{$typename}}ValueToName {
+        m[v], _ = json.Marshal(s)
+    }
+    return m
+}()
+{{else}}var _{{$typename}}ValueToJSON = map[{{$typename}}][]byte {
+    {{range $values}}{{if .Emit}}{{.Name}}: []byte({{printf "%q" .QuotedJSON}}),
+    {{end}}{{end}}
+}
+{{end}}
+{{end}}
+
+{{if .GenerateString}}
+// String is generated so {{$typename}} satisfies fmt.Stringer.
+func (r {{$typename}}) String() string {
+    if s, ok := _{{$typename}}ValueToName[r]; ok {
+        return s
+    }
+    {{if $.Flags}}if names, err := _{{$typename}}FlagNames(r); err == nil && len(names) > 0 {
+        return strings.Join(names, "|")
+    }
+    {{end}}return fmt.Sprintf("{{$typename}}({{if .IsString}}%q{{else}}%d{{end}})", {{template "raw" .}})
+}
+{{end}}
+
+{{if $.Flags}}
+// MarshalJSON is generated so {{$typename}} satisfies json.Marshaler. It
+// encodes the flags set in r as an array of their names.
+func (r {{$typename}}) MarshalJSON() ([]byte, error) {
+    names, err := _{{$typename}}FlagNames(r)
+    if err != nil {
+        return nil, err
     }
+    return json.Marshal(names)
 }
 
+// UnmarshalJSON is generated so {{$typename}} satisfies json.Unmarshaler. It
+// decodes an array of names into the value with all their flags set.
+func (r *{{$typename}}) UnmarshalJSON(data []byte) error {
+    var names []string
+    if err := json.Unmarshal(data, &names); err != nil {
+        return _{{$typename}}Error(string(data), "should be an array of strings")
+    }
+    v, err := _{{$typename}}ParseFlags(names)
+    if err != nil {
+        return err
+    }
+    *r = v
+    return nil
+}
+{{else}}
 // MarshalJSON is generated so {{$typename}} satisfies json.Marshaler.
 func (r {{$typename}}) MarshalJSON() ([]byte, error) {
-    if s, ok := interface{}(r).(fmt.Stringer); ok {
-        return json.Marshal(s.String())
+    if b, ok := _{{$typename}}ValueToJSON[r]; ok {
+        return append([]byte(nil), b...), nil
+    }
+    {{if and $.Open (or .IsString .AcceptNumbers)}}return json.Marshal({{.Underlying}}(r))
+    {{- else}}return nil, _{{$typename}}Error(fmt.Sprintf("{{if .IsString}}%q{{else}}%d{{end}}", {{template "raw" .}}), "")
+    {{- end}}
+}
+
+// UnmarshalJSON is generated so {{$typename}} satisfies json.Unmarshaler.
+func (r *{{$typename}}) UnmarshalJSON(data []byte) error {
+{{- if .AcceptNumbers}}
+    {{if .AcceptNames}}if len(data) > 0 && (data[0] == '-' || '0' <= data[0] && data[0] <= '9') {
+    {{end -}}
+    v, ok := _{{$typename}}NumberToValue[string(data)]
+    if !ok {
+        {{if $.Open}}var n {{.Underlying}}
+        if err := json.Unmarshal(data, &n); err != nil {
+            return _{{$typename}}Error(string(data), err.Error())
+        }
+        v = {{$typename}}(n)
+        {{- else if .Fallback}}{{if not .AcceptNames}}if len(data) == 0 || data[0] != '-' && (data[0] < '0' || '9' < data[0]) {
+            return _{{$typename}}Error(string(data), "should be a number")
+        }
+        {{end}}v = {{.Fallback}}
+        {{- else}}return _{{$typename}}Error(string(data), "{{if .AcceptNames}}not the value of any constant{{end}}")
+        {{- end}}
+    }
+    *r = v
+    return nil
+{{- if .AcceptNames}}
+    }
+{{end}}
+{{- end}}
+{{- if .AcceptNames}}
+    var name []byte
+    if n := len(data); n >= 2 && data[0] == '"' && data[n-1] == '"' && bytes.IndexByte(data, '\\') < 0 {
+        // Strings without escape sequences can be looked up without decoding.
+        name = data[1 : n-1]
+    } else {
+        var s string
+        if err := json.Unmarshal(data, &s); err != nil {
+            return _{{$typename}}Error(string(data), "should be a string{{if .AcceptNumbers}} or a number{{end}}")
+        }
+        name = []byte(s)
     }
+    v, ok := _{{$typename}}NameToValue[string(name)]
+    {{if $.Match.Normalize}}if !ok {
+        v, ok = _{{$typename}}NormalizedNameToValue[_{{$typename}}Normalize(string(name))]
+    }
+    {{end}}if !ok {
+        {{if and $.Open .IsString}}v = {{$typename}}(name)
+        {{- else if .Fallback}}v = {{.Fallback}}
+        {{- else}}return _{{$typename}}Error(string(data), "{{if .AcceptNumbers}}not the name of any constant{{end}}")
+        {{- end}}
+    }
+    *r = v
+    return nil
+{{- end}}
+}
+{{end}}
+
+{{if $.Text}}{{if $.Flags}}
+// MarshalText is generated so {{$typename}} satisfies encoding.TextMarshaler.
+// It joins the names of the flags set in r with "|".
+func (r {{$typename}}) MarshalText() ([]byte, error) {
+    names, err := _{{$typename}}FlagNames(r)
+    if err != nil {
+        return nil, err
+    }
+    return []byte(strings.Join(names, "|")), nil
+}
+
+// UnmarshalText is generated so {{$typename}} satisfies encoding.TextUnmarshaler.
+// It accepts names separated by "|".
+func (r *{{$typename}}) UnmarshalText(text []byte) error {
+    var names []string
+    if len(text) > 0 {
+        names = strings.Split(string(text), "|")
+    }
+    v, err := _{{$typename}}ParseFlags(names)
+    if err != nil {
+        return err
+    }
+    *r = v
+    return nil
+}
+{{else}}
+// MarshalText is generated so {{$typename}} satisfies encoding.TextMarshaler.
+func (r {{$typename}}) MarshalText() ([]byte, error) {
     s, ok := _{{$typename}}ValueToName[r]
     if !ok {
-        return nil, fmt.Errorf("invalid {{$typename}}: %d", r)
+        {{if and $.Open .IsString}}return []byte(r), nil
+        {{- else if $.Open}}return []byte(fmt.Sprintf("%d", r)), nil
+        {{- else}}return nil, _{{$typename}}Error(fmt.Sprintf("{{if .IsString}}%q{{else}}%d{{end}}", {{template "raw" .}}), "")
+        {{- end}}
     }
-    return json.Marshal(s)
+    return []byte(s), nil
 }
 
-// UnmarshalJSON is generated so {{$typename}} satisfies json.Unmarshaler.
-func (r *{{$typename}}) UnmarshalJSON(data []byte) error {
+// UnmarshalText is generated so {{$typename}} satisfies encoding.TextUnmarshaler.
+func (r *{{$typename}}) UnmarshalText(text []byte) error {
+    v, ok := _{{$typename}}NameToValue[string(text)]
+    {{if $.Match.Normalize}}if !ok {
+        v, ok = _{{$typename}}NormalizedNameToValue[_{{$typename}}Normalize(string(text))]
+    }
+    {{end}}if !ok {
+        {{if and $.Open .IsString}}v = {{$typename}}(text)
+        {{- else if $.Open}}n, err := strconv.ParseInt(string(text), 10, 64)
+        if err != nil || int64({{$typename}}(n)) != n {
+            return _{{$typename}}Error(string(text), "")
+        }
+        v = {{$typename}}(n)
+        {{- else if .Fallback}}v = {{.Fallback}}
+        {{- else}}return _{{$typename}}Error(string(text), "")
+        {{- end}}
+    }
+    *r = v
+    return nil
+}
+{{end}}{{end}}
+
+{{if $.SQL}}
+// Value is generated so {{$typename}} satisfies driver.Valuer. It returns
+// {{if .IntFormat}}the value as an integer{{else}}the name of the value{{end}}, as in JSON.
+func (r {{$typename}}) Value() (driver.Value, error) {
+    {{- if .IntFormat}}
+    {{if not $.Open}}if !r.IsValid() {
+        return nil, _{{$typename}}Error(fmt.Sprintf("%d", r), "")
+    }
+    {{end -}}
+    return int64(r), nil
+    {{- else}}
+    s, ok := _{{$typename}}ValueToName[r]
+    if !ok {
+        {{if and $.Open .IsString}}return string(r), nil
+        {{- else if $.Open}}return int64(r), nil
+        {{- else}}return nil, _{{$typename}}Error(fmt.Sprintf("{{if .IsString}}%q{{else}}%d{{end}}", {{template "raw" .}}), "")
+        {{- end}}
+    }
+    return s, nil
+    {{- end}}
+}
+
+// Scan is generated so {{$typename}} satisfies sql.Scanner. It accepts the
+// names of the constants as strings or byte slices{{if not .IsString}}, and their values as
+// integers or as strings of digits{{end}}.
+func (r *{{$typename}}) Scan(src interface{}) error {
     var s string
-    if err := json.Unmarshal(data, &s); err != nil {
-        return fmt.Errorf("{{$typename}} should be a string, got %s", data)
+    switch src := src.(type) {
+    case string:
+        s = src
+    case []byte:
+        s = string(src)
+    {{- if not .IsString}}
+    case int64:
+        v := {{$typename}}(src)
+        if int64(v) != src {
+            return _{{$typename}}Error(strconv.FormatInt(src, 10), "out of range")
+        }
+        {{if not $.Open}}if !v.IsValid() {
+            {{if .Fallback}}v = {{.Fallback}}
+            {{- else}}return _{{$typename}}Error(strconv.FormatInt(src, 10), "")
+            {{- end}}
+        }
+        {{end -}}
+        *r = v
+        return nil
+    {{- end}}
+    default:
+        return _{{$typename}}Error(fmt.Sprintf("%v", src), "should be a string{{if not .IsString}} or an integer{{end}}")
     }
-    v, ok := _{{$typename}}NameToValue[s]
+    v, err := Parse{{$typename}}(s)
+    if err != nil {
+        {{if not .IsString}}if n, perr := strconv.ParseInt(s, 10, 64); perr == nil {
+            return r.Scan(n)
+        }
+        {{end -}}
+        {{if and $.Open .IsString}}v = {{$typename}}(s)
+        {{- else if .Fallback}}v = {{.Fallback}}
+        {{- else}}return err
+        {{- end}}
+    }
+    *r = v
+    return nil
+}
+{{end}}
+
+{{if $.YAML}}
+// MarshalYAML is generated so {{$typename}} satisfies yaml.Marshaler.
+func (r {{$typename}}) MarshalYAML() (interface{}, error) {
+    {{- if .IntFormat}}
+    {{if not $.Open}}if !r.IsValid() {
+        return nil, _{{$typename}}Error(fmt.Sprintf("%d", r), "")
+    }
+    {{end -}}
+    return {{.Underlying}}(r), nil
+    {{- else}}
+    s, ok := _{{$typename}}ValueToName[r]
     if !ok {
-        return fmt.Errorf("invalid {{$typename}} %q", s)
+        {{if and $.Open (or .IsString .AcceptNumbers)}}return {{.Underlying}}(r), nil
+        {{- else}}return nil, _{{$typename}}Error(fmt.Sprintf("{{if .IsString}}%q{{else}}%d{{end}}", {{template "raw" .}}), "")
+        {{- end}}
+    }
+    return s, nil
+    {{- end}}
+}
+
+// UnmarshalYAML is generated so {{$typename}} satisfies yaml.Unmarshaler.
+func (r *{{$typename}}) UnmarshalYAML(value *yaml.Node) error {
+    if value.Kind != yaml.ScalarNode {
+        return _{{$typename}}Error(value.Tag, "should be a {{if .AcceptNames}}string{{if .AcceptNumbers}} or a {{end}}{{end}}{{if .AcceptNumbers}}number{{end}}")
+    }
+{{- if .AcceptNumbers}}
+    {{if .AcceptNames}}if value.Tag == "!!int" {
+    {{end -}}
+    var n {{.Underlying}}
+    if err := value.Decode(&n); err != nil {
+        return _{{$typename}}Error(value.Value, err.Error())
+    }
+    v := {{$typename}}(n)
+    {{if not $.Open}}if !v.IsValid() {
+        {{if .Fallback}}v = {{.Fallback}}
+        {{- else}}return _{{$typename}}Error(value.Value, "{{if .AcceptNames}}not the value of any constant{{end}}")
+        {{- end}}
+    }
+    {{end -}}
+    *r = v
+    return nil
+{{- if .AcceptNames}}
+    }
+{{end}}
+{{- end}}
+{{- if .AcceptNames}}
+    v, err := Parse{{$typename}}(value.Value)
+    if err != nil {
+        {{if and $.Open .IsString}}v = {{$typename}}(value.Value)
+        {{- else if .Fallback}}v = {{.Fallback}}
+        {{- else}}return err
+        {{- end}}
+    }
+    *r = v
+    return nil
+{{- end}}
+}
+{{end}}
+
+{{if $.XML}}
+// MarshalXML is generated so {{$typename}} satisfies xml.Marshaler.
+func (r {{$typename}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+    s, err := _{{$typename}}ToXML(r)
+    if err != nil {
+        return err
+    }
+    return e.EncodeElement(s, start)
+}
+
+// UnmarshalXML is generated so {{$typename}} satisfies xml.Unmarshaler.
+func (r *{{$typename}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+    var s string
+    if err := d.DecodeElement(&s, &start); err != nil {
+        return err
+    }
+    v, err := _{{$typename}}FromXML(s)
+    if err != nil {
+        return err
+    }
+    *r = v
+    return nil
+}
+
+// MarshalXMLAttr is generated so {{$typename}} satisfies xml.MarshalerAttr.
+func (r {{$typename}}) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+    s, err := _{{$typename}}ToXML(r)
+    if err != nil {
+        return xml.Attr{}, err
+    }
+    return xml.Attr{Name: name, Value: s}, nil
+}
+
+// UnmarshalXMLAttr is generated so {{$typename}} satisfies xml.UnmarshalerAttr.
+func (r *{{$typename}}) UnmarshalXMLAttr(attr xml.Attr) error {
+    v, err := _{{$typename}}FromXML(attr.Value)
+    if err != nil {
+        return err
     }
     *r = v
     return nil
 }
 
+// _{{$typename}}ToXML returns the text representing r in XML, which is the
+// same as in JSON.
+func _{{$typename}}ToXML(r {{$typename}}) (string, error) {
+    {{- if .IntFormat}}
+    if b, ok := _{{$typename}}ValueToJSON[r]; ok {
+        return string(b), nil
+    }
+    {{if $.Open}}return fmt.Sprintf("%d", r), nil
+    {{- else}}return "", _{{$typename}}Error(fmt.Sprintf("%d", r), "")
+    {{- end}}
+    {{- else}}
+    if s, ok := _{{$typename}}ValueToName[r]; ok {
+        return s, nil
+    }
+    {{if and $.Open .IsString}}return string(r), nil
+    {{- else if and $.Open .AcceptNumbers}}return fmt.Sprintf("%d", r), nil
+    {{- else}}return "", _{{$typename}}Error(fmt.Sprintf("{{if .IsString}}%q{{else}}%d{{end}}", {{template "raw" .}}), "")
+    {{- end}}
+    {{- end}}
+}
+
+// _{{$typename}}FromXML returns the value of {{$typename}} represented by the
+// given text in XML.
+func _{{$typename}}FromXML(s string) ({{$typename}}, error) {
+{{- if .AcceptNumbers}}
+    if v, ok := _{{$typename}}NumberToValue[s]; ok {
+        return v, nil
+    }
+    {{- if $.Open}}
+    if n, err := strconv.ParseInt(s, 10, 64); err == nil && int64({{$typename}}(n)) == n {
+        return {{$typename}}(n), nil
+    }
+    {{- end}}
+{{- end}}
+{{- if .AcceptNames}}
+    if v, err := Parse{{$typename}}(s); err == nil {
+        return v, nil
+    }
+{{- end}}
+    {{if and $.Open .IsString}}return {{$typename}}(s), nil
+    {{- else if .Fallback}}return {{.Fallback}}, nil
+    {{- else}}var v {{$typename}}
+    return v, _{{$typename}}Error(s, "")
+    {{- end}}
+}
+{{end}}
+
+{{if and $.Open .AcceptNames (not .IsString)}}
+// Open{{$typename}} is a {{$typename}} that keeps the names unknown to this
+// version of the package when decoding JSON, so they're encoded unchanged.
+type Open{{$typename}} struct {
+    Value {{$typename}}
+    // Unknown is the JSON encoding of a name that isn't the one of any
+    // constant of {{$typename}}, or nil if Value holds the decoded value.
+    Unknown json.RawMessage
+}
+
+// MarshalJSON is generated so Open{{$typename}} satisfies json.Marshaler.
+func (o Open{{$typename}}) MarshalJSON() ([]byte, error) {
+    if o.Unknown != nil {
+        return o.Unknown, nil
+    }
+    return o.Value.MarshalJSON()
+}
+
+// UnmarshalJSON is generated so Open{{$typename}} satisfies json.Unmarshaler.
+func (o *Open{{$typename}}) UnmarshalJSON(data []byte) error {
+    var v {{$typename}}
+    if err := v.UnmarshalJSON(data); err != nil {
+        var s string
+        if json.Unmarshal(data, &s) != nil {
+            return err
+        }
+        o.Value, o.Unknown = v, append(json.RawMessage(nil), data...)
+        return nil
+    }
+    o.Value, o.Unknown = v, nil
+    return nil
+}
+{{end}}
+
+// {{$typename}}Values returns all the values of {{$typename}}, in the order
+// they are declared.
+func {{$typename}}Values() []{{$typename}} {
+    return []{{$typename}}{
+        {{range $values}}{{if .Emit}}{{.Name}},
+        {{end}}{{end}}
+    }
+}
+
+// {{$typename}}Names returns the names of all the values of {{$typename}}, in
+// the order they are declared.
+func {{$typename}}Names() []string {
+    return append([]string(nil), _{{$typename}}Names...)
+}
+
+// IsValid reports whether r is the value of one of the constants of
+// {{$typename}}{{if $.Flags}}, or a combination of them{{end}}.
+func (r {{$typename}}) IsValid() bool {
+    {{- if $.Flags}}
+    return r&^_{{$typename}}Mask == 0
+    {{- else}}
+    _, ok := _{{$typename}}ValueToName[r]
+    return ok
+    {{- end}}
+}
+
+// Parse{{$typename}} returns the value of {{$typename}} with the given name.
+func Parse{{$typename}}(name string) ({{$typename}}, error) {
+    v, ok := _{{$typename}}NameToValue[name]
+    {{if $.Match.Normalize}}if !ok {
+        v, ok = _{{$typename}}NormalizedNameToValue[_{{$typename}}Normalize(name)]
+    }
+    {{end}}if !ok {
+        return v, _{{$typename}}Error(name, "")
+    }
+    return v, nil
+}
+
 {{end}}
+
+{{define "name"}}{{if .FromString}}{{.Name}}.String(){{else}}{{printf "%q" .JSONName}}{{end}}{{end}}
+
+{{define "raw"}}{{if .IsString}}string(r){{else}}r{{end}}{{end}}
 `))
@@ -0,0 +1,119 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A transform changes the name of a constant into its JSON representation.
+type transform func(name string) string
+
+// transforms contains all the transforms accepted by the -transform flag.
+var transforms = map[string]transform{
+	"none":  func(name string) string { return name },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"snake": func(name string) string {
+		return strings.ToLower(strings.Join(splitWords(name), "_"))
+	},
+	"screaming_snake": func(name string) string {
+		return strings.ToUpper(strings.Join(splitWords(name), "_"))
+	},
+	"kebab": func(name string) string {
+		return strings.ToLower(strings.Join(splitWords(name), "-"))
+	},
+	"camel": func(name string) string {
+		words := splitWords(name)
+		for i, w := range words {
+			if i == 0 {
+				words[i] = strings.ToLower(w)
+			} else {
+				words[i] = title(w)
+			}
+		}
+		return strings.Join(words, "")
+	},
+	"pascal": func(name string) string {
+		words := splitWords(name)
+		for i, w := range words {
+			words[i] = title(w)
+		}
+		return strings.Join(words, "")
+	},
+}
+
+// parseTransform returns the transform with the given name.
+func parseTransform(name string) (transform, error) {
+	if t, ok := transforms[name]; ok {
+		return t, nil
+	}
+	var names []string
+	for name := range transforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown transform %q, must be one of %s", name, strings.Join(names, ", "))
+}
+
+// splitWords splits an identifier into words, breaking on underscores and on
+// changes of case, so that "StatusInProgress" becomes "Status", "In" and
+// "Progress", and "HTTPServer_v2" becomes "HTTP", "Server" and "v2".
+// Digits belong to the word they follow.
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && runes[i] != '_' && !isWordStart(runes, i) {
+			continue
+		}
+		if i > start {
+			words = append(words, string(runes[start:i]))
+		}
+		start = i
+		if i < len(runes) && runes[i] == '_' {
+			start++
+		}
+	}
+	return words
+}
+
+// isWordStart reports whether the rune at position i of an identifier starts
+// a new word: an upper case letter after a lower case letter or digit, or the
+// last upper case letter of an acronym followed by a lower case letter.
+func isWordStart(runes []rune, i int) bool {
+	if i == 0 || !unicode.IsUpper(runes[i]) {
+		return false
+	}
+	prev := runes[i-1]
+	if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+}
+
+// title returns the word with its first letter in upper case and the rest in
+// lower case.
+func title(word string) string {
+	if word == "" {
+		return word
+	}
+	runes := []rune(strings.ToLower(word))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,50 @@
+// Copyright 2017 Google Inc. All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to writing, software distributed
+// under the License is distributed on a "AS IS" BASIS, WITHOUT WARRANTIES OR
+// CONDITIONS OF ANY KIND, either express or implied.
+//
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		transform string
+		in, out   string
+	}{
+		{"none", "StatusInProgress", "StatusInProgress"},
+		{"lower", "StatusInProgress", "statusinprogress"},
+		{"upper", "StatusInProgress", "STATUSINPROGRESS"},
+		{"snake", "StatusInProgress", "status_in_progress"},
+		{"snake", "HTTPServer", "http_server"},
+		{"snake", "Level2Cache", "level2_cache"},
+		{"snake", "already_snake", "already_snake"},
+		{"screaming_snake", "InProgress", "IN_PROGRESS"},
+		{"kebab", "InProgress", "in-progress"},
+		{"camel", "InProgress", "inProgress"},
+		{"camel", "HTTPServer", "httpServer"},
+		{"pascal", "in_progress", "InProgress"},
+		{"pascal", "XL", "Xl"},
+	}
+	for _, tt := range tests {
+		tr, err := parseTransform(tt.transform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tr(tt.in); got != tt.out {
+			t.Errorf("%s(%q): expected %q; got %q", tt.transform, tt.in, tt.out, got)
+		}
+	}
+
+	if _, err := parseTransform("title"); err == nil {
+		t.Errorf("expected error for unknown transform")
+	}
+}